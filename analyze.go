@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"generator/config"
+	"generator/models"
+	"generator/parse"
+	"generator/processor"
+	"log"
+	"math"
+	"math/big"
+)
+
+const analysisFile = "./assets/results/analysis.json" // Feasibility report of the trait pools
+
+// AnalysisReport is the feasibility report of the trait pools, by segment.
+type AnalysisReport struct {
+	Segments []SegmentReport `json:"segments"` // Every analyzed segment.
+	Issues   int             `json:"issues"`   // Number of issues over all segments.
+}
+
+// SegmentReport describes the tokens of one specie, gender, category and rarity.
+type SegmentReport struct {
+	Specie         models.Specie   `json:"specie"`
+	Gender         models.Gender   `json:"gender"`
+	Category       models.Category `json:"category"`
+	Rarity         models.Rarity   `json:"rarity"`
+	Tokens         int             `json:"tokens"`          // Tokens of the specie and rarity in the collection.
+	ExpectedTokens float64         `json:"expected_tokens"` // Share of these tokens expected to draw the gender and category.
+	Combinations   string          `json:"combinations"`    // Upper bound of the unique combinations of the segment.
+	Slots          []SlotPool      `json:"slots"`           // Candidate pool of every slot picked for the segment.
+	Issues         []string        `json:"issues"`          // Empty pools and lack of combinations.
+}
+
+// SlotPool is the candidate pool of a slot within a segment.
+type SlotPool struct {
+	Slot       models.Slot `json:"slot"`
+	Mandatory  bool        `json:"mandatory"`  // Whether selection can force an item of the slot.
+	Candidates int         `json:"candidates"` // Number of items that can be picked.
+	Values     []string    `json:"values"`     // Trait values of these items.
+}
+
+// executeAnalyze enumerates every specie, gender, category and rarity, and
+// reports the items each slot can pick from. Only the segments with tokens
+// in the collection are analyzed, unless all is set.
+func executeAnalyze(all bool) {
+	tr := parse.Do()
+
+	tokens := make(map[models.Specie]map[models.Rarity]int)
+	for _, response := range responses {
+		specie, rarity := response.GetSpecie(), response.GetRarity()
+		if specie == models.SpecieNone || rarity.IsOneOfOne() {
+			continue
+		}
+		if tokens[specie] == nil {
+			tokens[specie] = make(map[models.Rarity]int)
+		}
+		tokens[specie][rarity]++
+	}
+
+	report := AnalysisReport{Segments: []SegmentReport{}}
+	for _, specie := range models.SpecieList() {
+		for _, rarity := range models.RarityList() {
+			if !all && tokens[specie][rarity] == 0 {
+				continue
+			}
+
+			probabilities := config.Get().Probabilities.For(specie, rarity)
+			for _, gender := range models.GenderList() {
+				for _, category := range models.CategoryList() {
+					c := tr.Copy()
+					c.Final.Specie = specie
+					c.Final.Rarity = rarity
+					c.Final.Gender = gender
+					c.Final.Category = category
+
+					segment := analyzeSegment(c, tokens[specie][rarity], probabilities.Hair[gender])
+					segment.ExpectedTokens = float64(segment.Tokens) *
+						share(probabilities.Gender, gender) *
+						share(probabilities.Category, category)
+					checkCombinations(&segment)
+
+					report.Issues += len(segment.Issues)
+					report.Segments = append(report.Segments, segment)
+				}
+			}
+		}
+	}
+
+	writeToSimpleFile(analysisFile, report)
+
+	log.Printf("Analyzed %d segments, %d issues", len(report.Segments), report.Issues)
+	for _, segment := range report.Segments {
+		for _, issue := range segment.Issues {
+			log.Printf("%s %s %s %s: %s", segment.Specie, segment.Gender, segment.Category, segment.Rarity, issue)
+		}
+	}
+}
+
+// analyzeSegment reports the pools of the segment of c.Final, whose chance of
+// having hair is hairChance, in percent.
+func analyzeSegment(c *models.Traits, tokens int, hairChance float64) SegmentReport {
+	segment := SegmentReport{
+		Specie:   c.Final.Specie,
+		Gender:   c.Final.Gender,
+		Category: c.Final.Category,
+		Rarity:   c.Final.Rarity,
+		Tokens:   tokens,
+		Slots:    []SlotPool{},
+		Issues:   []string{},
+	}
+
+	combinations := big.NewInt(1)
+	for _, slot := range processor.PickedSlots() {
+		data, ok := processor.Pool(c, slot)
+		if !ok {
+			continue
+		}
+
+		pool := SlotPool{
+			Slot:       slot,
+			Mandatory:  processor.IsMandatory(c, slot, hairChance),
+			Candidates: len(data),
+			Values:     []string{},
+		}
+		for _, common := range data {
+			pool.Values = append(pool.Values, common.OpenSeaTraitValue)
+		}
+		segment.Slots = append(segment.Slots, pool)
+
+		// An optional slot can also be left empty.
+		options := int64(pool.Candidates)
+		if !pool.Mandatory {
+			options++
+		}
+		combinations.Mul(combinations, big.NewInt(options))
+
+		if pool.Candidates == 0 {
+			if pool.Mandatory {
+				segment.Issues = append(segment.Issues, fmt.Sprintf("no %s can be picked", slot))
+			} else {
+				segment.Issues = append(segment.Issues, fmt.Sprintf("empty %s pool", slot))
+			}
+		}
+
+		// Without a droplet, no other slot is picked.
+		if slot == models.SlotDroplets && pool.Candidates == 0 {
+			break
+		}
+	}
+	segment.Combinations = combinations.String()
+
+	return segment
+}
+
+// checkCombinations flags a segment that cannot produce a unique combination
+// for each of its expected tokens.
+func checkCombinations(segment *SegmentReport) {
+	expected := big.NewInt(int64(math.Ceil(segment.ExpectedTokens)))
+	combinations, _ := new(big.Int).SetString(segment.Combinations, 10)
+
+	if combinations.Cmp(expected) < 0 {
+		segment.Issues = append(segment.Issues,
+			fmt.Sprintf("%s combinations for %s expected tokens", combinations, expected))
+	}
+}
+
+// share returns the probability of a value of a weight table.
+func share[T comparable](weights map[T]float64, value T) float64 {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total == 0 {
+		return 0
+	}
+	return weights[value] / total
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"generator/arweave"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// arweaveBackend writes an Arweave path manifest mapping the uploaded files
+// to their transactions. The manifest ID is only known once the manifest has
+// been uploaded, so the metadata is rewritten by a second run given that ID.
+type arweaveBackend struct {
+	mapping    string // JSON file mapping paths such as "0.png" to transaction IDs.
+	output     string // Manifest file to write.
+	index      string // Path served at the root of the manifest, if any.
+	manifestID string // Transaction ID of the uploaded manifest, if known.
+	nrNFTs     int    // Number of tokens expected in the mapping.
+}
+
+// PackImages writes the manifest of the mapped files and returns the manifest ID as "{manifest}", if known.
+func (b *arweaveBackend) PackImages() (map[string]string, error) {
+	mapping, err := arweave.ReadMapping(b.mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := arweave.CheckMapping(mapping, b.nrNFTs); err != nil {
+		return nil, err
+	}
+
+	manifest, err := arweave.NewManifest(mapping, b.index)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(b.output), 0755); err != nil {
+		return nil, err
+	}
+	writeToSimpleFile(b.output, manifest)
+	log.Printf("Wrote the manifest of %d paths to %s", len(manifest.Paths), b.output)
+
+	if b.manifestID == "" {
+		return nil, nil
+	}
+	if !arweave.IsTxID(b.manifestID) {
+		return nil, fmt.Errorf("invalid manifest ID: %q", b.manifestID)
+	}
+
+	return map[string]string{"manifest": b.manifestID}, nil
+}
+
+// PackMetadata does nothing: the rewritten metadata is uploaded, then mapped by another manifest.
+func (b *arweaveBackend) PackMetadata() error {
+	return nil
+}
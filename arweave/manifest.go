@@ -0,0 +1,123 @@
+package arweave
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Identifiers of the path manifest format.
+const (
+	ManifestType    = "arweave/paths"
+	ManifestVersion = "0.1.0"
+)
+
+// txID matches an Arweave transaction ID: 32 bytes in unpadded base64url.
+var txID = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+
+// Manifest is an Arweave path manifest. Once uploaded, "ar://{manifest}/{path}"
+// resolves to the transaction mapped to the path.
+type Manifest struct {
+	Manifest string          `json:"manifest"`        // Always ManifestType.
+	Version  string          `json:"version"`         // Always ManifestVersion.
+	Index    *Index          `json:"index,omitempty"` // Path served at the root of the manifest, if any.
+	Paths    map[string]Path `json:"paths"`           // Transaction of every path.
+}
+
+// Index names the path served at the root of a manifest.
+type Index struct {
+	Path string `json:"path"`
+}
+
+// Path points to the transaction holding the data of a path.
+type Path struct {
+	ID string `json:"id"`
+}
+
+// NewManifest builds a manifest from a mapping of paths to transaction IDs.
+// An empty index leaves the root of the manifest unmapped.
+func NewManifest(mapping map[string]string, index string) (*Manifest, error) {
+	manifest := &Manifest{
+		Manifest: ManifestType,
+		Version:  ManifestVersion,
+		Paths:    make(map[string]Path, len(mapping)),
+	}
+
+	// Sorted so errors are reported in a stable order.
+	paths := make([]string, 0, len(mapping))
+	for path := range mapping {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if path == "" || path[0] == '/' {
+			return nil, fmt.Errorf("invalid path: %q", path)
+		}
+		if !txID.MatchString(mapping[path]) {
+			return nil, fmt.Errorf("invalid transaction ID of %s: %q", path, mapping[path])
+		}
+		manifest.Paths[path] = Path{ID: mapping[path]}
+	}
+
+	if index != "" {
+		if _, ok := manifest.Paths[index]; !ok {
+			return nil, fmt.Errorf("index %s is not in the manifest", index)
+		}
+		manifest.Index = &Index{Path: index}
+	}
+
+	return manifest, nil
+}
+
+// CheckMapping checks that the image of every one of the count tokens is
+// mapped, and its metadata too when the mapping holds any metadata file.
+func CheckMapping(mapping map[string]string, count int) error {
+	var hasMetadata bool
+	for path := range mapping {
+		if strings.HasSuffix(path, ".json") {
+			hasMetadata = true
+			break
+		}
+	}
+
+	var missing []string
+	for tokenID := 0; tokenID < count; tokenID++ {
+		if _, ok := mapping[fmt.Sprintf("%d.png", tokenID)]; !ok {
+			missing = append(missing, fmt.Sprintf("%d.png", tokenID))
+		}
+		if _, ok := mapping[fmt.Sprintf("%d.json", tokenID)]; hasMetadata && !ok {
+			missing = append(missing, fmt.Sprintf("%d.json", tokenID))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%d files are not mapped, such as %s", len(missing), missing[0])
+	}
+
+	return nil
+}
+
+// ReadMapping reads a JSON object mapping paths to transaction IDs, such as
+// {"0.png": "<tx id>", "0.json": "<tx id>"}.
+func ReadMapping(name string) (map[string]string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping map[string]string
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", name, err)
+	}
+
+	return mapping, nil
+}
+
+// IsTxID reports whether id is a well-formed transaction ID.
+func IsTxID(id string) bool {
+	return txID.MatchString(id)
+}
@@ -0,0 +1,109 @@
+package arweave
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Well-formed transaction IDs.
+const (
+	imageTx    = "Bk0KaQ4tQ6XKNMEgc9Dj1xG5rXYNR7T3rqp4bhV5b0k"
+	metadataTx = "cG9c5Wx4ERVpjqEBlGI2XA0cpkKOtlSpOe-v_5YuzKc"
+)
+
+func TestNewManifestJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		index    string
+		expected string
+	}{
+		{
+			name: "without index",
+			expected: `{"manifest":"arweave/paths","version":"0.1.0",` +
+				`"paths":{"0.json":{"id":"` + metadataTx + `"},"0.png":{"id":"` + imageTx + `"}}}`,
+		},
+		{
+			name:  "with index",
+			index: "0.json",
+			expected: `{"manifest":"arweave/paths","version":"0.1.0","index":{"path":"0.json"},` +
+				`"paths":{"0.json":{"id":"` + metadataTx + `"},"0.png":{"id":"` + imageTx + `"}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manifest, err := NewManifest(map[string]string{"0.png": imageTx, "0.json": metadataTx}, test.index)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := json.Marshal(manifest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.expected {
+				t.Errorf("manifest = %s\nexpected %s", data, test.expected)
+			}
+		})
+	}
+}
+
+func TestNewManifestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping map[string]string
+		index   string
+		error   string
+	}{
+		{name: "short transaction ID", mapping: map[string]string{"0.png": imageTx[1:]}, error: "invalid transaction ID"},
+		{name: "long transaction ID", mapping: map[string]string{"0.png": imageTx + "A"}, error: "invalid transaction ID"},
+		{name: "padded transaction ID", mapping: map[string]string{"0.png": imageTx[1:] + "="}, error: "invalid transaction ID"},
+		{name: "base64 transaction ID", mapping: map[string]string{"0.png": imageTx[1:] + "+"}, error: "invalid transaction ID"},
+		{name: "leading slash", mapping: map[string]string{"/0.png": imageTx}, error: "invalid path"},
+		{name: "empty path", mapping: map[string]string{"": imageTx}, error: "invalid path"},
+		{name: "unknown index", mapping: map[string]string{"0.png": imageTx}, index: "index.html", error: "not in the manifest"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewManifest(test.mapping, test.index)
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				t.Errorf("NewManifest() = %v, expected an error containing %q", err, test.error)
+			}
+		})
+	}
+}
+
+func TestCheckMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping map[string]string
+		error   string
+	}{
+		{name: "images", mapping: map[string]string{"0.png": imageTx, "1.png": imageTx}},
+		{name: "images and metadata", mapping: map[string]string{
+			"0.png": imageTx, "1.png": imageTx, "0.json": metadataTx, "1.json": metadataTx,
+		}},
+		{name: "missing image", mapping: map[string]string{"0.png": imageTx}, error: "1 files are not mapped, such as 1.png"},
+		{name: "missing metadata", mapping: map[string]string{
+			"0.png": imageTx, "1.png": imageTx, "0.json": metadataTx,
+		}, error: "1 files are not mapped, such as 1.json"},
+		{name: "empty", mapping: map[string]string{}, error: "2 files are not mapped, such as 0.png"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckMapping(test.mapping, 2)
+			if test.error == "" {
+				if err != nil {
+					t.Errorf("CheckMapping() = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.error {
+				t.Errorf("CheckMapping() = %v, expected %q", err, test.error)
+			}
+		})
+	}
+}
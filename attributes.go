@@ -0,0 +1,36 @@
+package main
+
+import (
+	"generator/config"
+	"generator/models"
+)
+
+// slotAttribute returns the metadata attribute reporting the item picked for a
+// slot, following the slot mapping of the configuration. Returns false when
+// the slot does not emit an attribute.
+func slotAttribute(slot models.Slot, common *models.Common) (models.Attribute, bool) {
+	mapping := config.Get().Slots[slot]
+	return mapping.Attribute(slot.TraitType(), slot.TraitType() != "", common.OpenSeaTraitValue)
+}
+
+// derivedAttributes returns the metadata attributes reporting the values
+// decided during selection, such as the gender, that the configuration
+// asks to emit. Values that were not decided are left out.
+func derivedAttributes(final models.FinalTraits) []models.Attribute {
+	var attributes []models.Attribute
+
+	for _, derived := range models.DerivedList() {
+		if attribute, ok := derivedAttribute(derived, final); ok {
+			attributes = append(attributes, attribute)
+		}
+	}
+
+	return attributes
+}
+
+// derivedAttribute returns the metadata attribute reporting a derived value.
+// Returns false when the value is not emitted or was not decided.
+func derivedAttribute(derived models.Derived, final models.FinalTraits) (models.Attribute, bool) {
+	mapping := config.Get().Derived[derived]
+	return mapping.Attribute(derived.String(), false, final.Derived(derived))
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"generator/collector"
+	"generator/config"
+	"generator/models"
+	"generator/parse"
+	"generator/processor"
+	"log"
+	"strconv"
+	"strings"
+)
+
+const auditFile = "./assets/results/audit.json" // Compatibility rules broken by the generated tokens
+
+// AuditReport lists the compatibility rules broken by the generated tokens.
+type AuditReport struct {
+	Tokens     int              `json:"tokens"`     // Number of audited tokens.
+	Violations map[int][]string `json:"violations"` // Compatibility rules broken, and traits that could not be recovered, by token.
+}
+
+// executeAudit checks the first nrNFTs tokens against the compatibility rules,
+// so a regression of the selection rules is caught on an existing collection.
+// The traits of a token are taken from the layers recorded in the plan or
+// manifest, which name every slot exactly. Tokens missing from it are
+// recovered from the attributes of their metadata, so only the slots reported
+// there are checked, and an attribute several items could have produced is
+// reported instead of guessed.
+func executeAudit(nrNFTs int, planName string) {
+	tr := parse.Do()
+
+	recorded := make(map[int]TokenSpec)
+	if specs, err := readPlan(planName); err != nil {
+		log.Printf("Auditing from the metadata only: %s", err)
+	} else {
+		for _, spec := range specs {
+			recorded[spec.TokenID] = spec
+		}
+	}
+
+	report := AuditReport{Violations: make(map[int][]string)}
+	for tokenID := 0; tokenID < nrNFTs && tokenID < len(responses); tokenID++ {
+		if responses[tokenID].GetRarity().IsOneOfOne() {
+			continue
+		}
+
+		var errs []error
+		if spec, ok := recorded[tokenID]; ok && len(spec.Layers) > 0 {
+			_, errs = resolveSpec(tr, spec)
+		} else {
+			metadata, err := collector.GetMetadataWithError(strconv.Itoa(tokenID))
+			if err != nil {
+				log.Printf("File not found: %d: %s", tokenID, err)
+				continue
+			}
+
+			var final models.FinalTraits
+			final, errs = traitsFromMetadata(tr, metadata)
+			errs = append(errs, processor.Validate(tr, final)...)
+		}
+		report.Tokens++
+
+		for _, err := range errs {
+			log.Printf("Token %d: %s", tokenID, err)
+			report.Violations[tokenID] = append(report.Violations[tokenID], err.Error())
+		}
+	}
+
+	writeToSimpleFile(auditFile, report)
+
+	log.Printf("Audited %d tokens, %d breaking the rules", report.Tokens, len(report.Violations))
+}
+
+// attributeMatch is an item whose attribute a metadata reports.
+type attributeMatch struct {
+	slot   models.Slot
+	common *models.Common
+}
+
+// traitsFromMetadata recovers the traits of a token from its metadata: a slot
+// gets the item whose attribute the metadata reports, and the gender is set
+// when it is reported too. Several slots share a trait type, so an attribute
+// can match items of several slots, or several items of one slot; such an
+// attribute is returned as an error and none of its items is set. The second
+// layer of a combined item is set along with it.
+func traitsFromMetadata(traits *models.Traits, metadata *models.APIResponse) (models.FinalTraits, []error) {
+	reported := reportedAttributes(metadata)
+
+	final := models.FinalTraits{
+		Metadata: metadata,
+		Rarity:   metadata.GetRarity(),
+		Specie:   metadata.GetSpecie(),
+	}
+
+	for _, gender := range models.GenderList() {
+		if attribute, ok := derivedAttribute(models.DerivedGender, models.FinalTraits{Gender: gender}); ok && reported[attribute] {
+			final.Gender = gender
+		}
+	}
+
+	var attributes []models.Attribute
+	matches := make(map[models.Attribute][]attributeMatch)
+	for _, slot := range models.SlotList() {
+		for _, common := range traits.Candidates(slot) {
+			if attribute, ok := slotAttribute(slot, common); ok && reported[attribute] {
+				if len(matches[attribute]) == 0 {
+					attributes = append(attributes, attribute)
+				}
+				matches[attribute] = append(matches[attribute], attributeMatch{slot: slot, common: common})
+			}
+		}
+	}
+
+	var errs []error
+	for _, attribute := range attributes {
+		candidates := withoutCombinedBacks(matches[attribute])
+		if len(candidates) > 1 {
+			var names []string
+			for _, match := range candidates {
+				names = append(names, fmt.Sprintf("%s %q", match.slot, match.common.FileName))
+			}
+			errs = append(errs, fmt.Errorf("attribute %s: %s is ambiguous, it matches %s",
+				attribute.TraitType, attribute.Value, strings.Join(names, ", ")))
+			continue
+		}
+
+		match := candidates[0]
+		final.Set(match.slot, match.common)
+		if back, ok := processor.CombinedBack(match.slot); ok && match.common.Combined.Bool() {
+			final.Set(back, processor.ExtractByTraitValue(traits.Candidates(back), match.common.OpenSeaTraitValue))
+		}
+	}
+
+	return final, errs
+}
+
+// withoutCombinedBacks drops the matches that are the second layer of a
+// combined item matched too, as both layers report the same attribute.
+func withoutCombinedBacks(matches []attributeMatch) []attributeMatch {
+	var result []attributeMatch
+	for _, match := range matches {
+		isBack := false
+		for _, front := range matches {
+			back, ok := processor.CombinedBack(front.slot)
+			if ok && back == match.slot && front.common.Combined.Bool() &&
+				front.common.OpenSeaTraitValue == match.common.OpenSeaTraitValue {
+				isBack = true
+			}
+		}
+		if !isBack {
+			result = append(result, match)
+		}
+	}
+	return result
+}
+
+// reportedAttributes returns the attributes of the metadata, with the values
+// merged by the join policy split back apart.
+func reportedAttributes(metadata *models.APIResponse) map[models.Attribute]bool {
+	opts := config.Get().AttributeOptions()
+
+	separator := opts.Separator
+	if separator == "" {
+		separator = ", "
+	}
+
+	reported := make(map[models.Attribute]bool)
+	for _, attribute := range metadata.Attributes {
+		reported[attribute] = true
+
+		if opts.Merge[attribute.TraitType] == models.MergeJoin {
+			for _, value := range strings.Split(attribute.Value, separator) {
+				reported[models.Attribute{TraitType: attribute.TraitType, Value: value}] = true
+			}
+		}
+	}
+
+	return reported
+}
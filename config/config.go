@@ -0,0 +1,240 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"generator/models"
+	"generator/storage"
+	"generator/utils"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// filePath is the location of the optional configuration file.
+const filePath = "config.json"
+
+// Config holds the settings of the generator that are not part of the spreadsheet.
+// Every setting has a default, so the configuration file only lists overrides.
+type Config struct {
+	Profile    models.Profile          `json:"profile"`    // Profile used when exporting metadata.
+	Metadata   models.ProfileOptions   `json:"metadata"`   // Collection-wide values added by the profiles.
+	Attributes models.AttributeOptions `json:"attributes"` // Order and merging of the metadata attributes.
+
+	Slots   map[models.Slot]models.AttributeMapping    `json:"slots"`   // Attribute reported for each slot, by slot.
+	Derived map[models.Derived]models.AttributeMapping `json:"derived"` // Attribute reported for derived values such as the gender.
+
+	Properties bool `json:"properties"` // Record the category, gender and hair in a properties block of the metadata.
+
+	Probabilities models.ProbabilityTables `json:"probabilities"` // Category, gender and hair chances.
+
+	RandomSource utils.SourceKind `json:"random_source"` // Random source of new runs: sha1, sha1-legacy, pcg or keccak.
+
+	Placeholder Placeholder `json:"placeholder"` // Pre-reveal metadata.
+
+	Output    storage.Config `json:"output"`      // Where generation writes the images and metadata.
+	OneOfOnes string         `json:"one_of_ones"` // Folder of the pre-made PNG artwork of hand-made tokens, named {tokenID}.png.
+
+	attributeOptions models.AttributeOptions // Attribute options completed with the mapping policies, set by load.
+}
+
+// Placeholder holds the pre-reveal metadata shared by every token.
+// "{id}" is replaced by the token ID in every text.
+type Placeholder struct {
+	Name            string `json:"name"`             // Name pattern, such as "Seizon #{id}".
+	Description     string `json:"description"`      // Description of the unrevealed token.
+	Image           string `json:"image"`            // URI of the placeholder image.
+	AnimationURL    string `json:"animation_url"`    // Optional URI of a placeholder animation.
+	StatusAttribute bool   `json:"status_attribute"` // Add a "Status: Unrevealed" attribute.
+}
+
+var (
+	once   sync.Once
+	config *Config
+)
+
+// Get returns the configuration, reading the configuration file on first use.
+func Get() *Config {
+	once.Do(func() {
+		config = load()
+	})
+	return config
+}
+
+// Default returns the configuration used when no configuration file exists.
+func Default() *Config {
+	return &Config{
+		Profile:      models.ProfileDefault,
+		RandomSource: utils.SourceSHA1,
+		Placeholder: Placeholder{
+			Name: "Unrevealed #{id}",
+		},
+		Output: storage.Config{
+			Type: storage.KindLocal,
+			Path: "./assets/results",
+		},
+		OneOfOnes: "./assets/one_of_ones",
+	}
+}
+
+// load reads the configuration file over the defaults.
+func load() *Config {
+	result := Default()
+
+	data, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		result.attributeOptions = mergeAttributeOptions(result)
+		return result
+	}
+	if err != nil {
+		// Panic if the file exists but cannot be read, as this is a critical error.
+		panic(fmt.Errorf("reading config: %w", err))
+	}
+
+	if err := json.Unmarshal(data, result); err != nil {
+		panic(fmt.Errorf("parsing config: %w", err))
+	}
+
+	if result.Profile.IsInvalid() {
+		panic(fmt.Errorf("invalid profile: %s", result.Profile))
+	}
+
+	if result.RandomSource.IsInvalid() {
+		panic(fmt.Errorf("invalid random source: %s", result.RandomSource))
+	}
+
+	if result.Output.Type.IsInvalid() {
+		panic(fmt.Errorf("invalid output type: %s", result.Output.Type))
+	}
+	if result.Output.Type != storage.KindS3 && result.Output.Path == "" {
+		panic(fmt.Errorf("output path is missing"))
+	}
+	if result.OneOfOnes == "" {
+		panic(fmt.Errorf("one_of_ones folder is missing"))
+	}
+
+	for traitType, policy := range result.Attributes.Merge {
+		if policy.IsInvalid() {
+			panic(fmt.Errorf("invalid merge policy: %s for %s", policy, traitType))
+		}
+	}
+
+	for slot, mapping := range result.Slots {
+		if slot.IsInvalid() {
+			panic(fmt.Errorf("invalid slot: %s", slot))
+		}
+		if mapping.Merge.IsInvalid() {
+			panic(fmt.Errorf("invalid merge policy: %s for %s", mapping.Merge, slot))
+		}
+		if mapping.Emit != nil && *mapping.Emit && mapping.TraitType == "" && slot.TraitType() == "" {
+			panic(fmt.Errorf("slot %s emits an attribute but has no trait_type", slot))
+		}
+	}
+
+	validateProbabilities(result.Probabilities.Default)
+	for specie, p := range result.Probabilities.BySpecie {
+		if specie.IsInvalid() || specie == models.SpecieNone {
+			panic(fmt.Errorf("invalid specie: %s", specie))
+		}
+		validateProbabilities(p)
+	}
+	for rarity, p := range result.Probabilities.ByRarity {
+		if rarity.IsInvalid() {
+			panic(fmt.Errorf("invalid rarity: %s", rarity))
+		}
+		validateProbabilities(p)
+	}
+
+	for derived, mapping := range result.Derived {
+		if mapping.Merge.IsInvalid() {
+			panic(fmt.Errorf("invalid merge policy: %s for %s", mapping.Merge, derived))
+		}
+	}
+
+	result.attributeOptions = mergeAttributeOptions(result)
+
+	return result
+}
+
+// validateProbabilities panics if a probability table names an unknown value,
+// has a negative weight or has no weight at all.
+func validateProbabilities(p models.Probabilities) {
+	var total float64
+	for category, weight := range p.Category {
+		if category.IsInvalid() || category == models.CategoryNA || weight < 0 {
+			panic(fmt.Errorf("invalid category probability: %s %v", category, weight))
+		}
+		total += weight
+	}
+	if len(p.Category) > 0 && total == 0 {
+		panic(fmt.Errorf("category probabilities sum up to 0"))
+	}
+
+	total = 0
+	for gender, weight := range p.Gender {
+		if gender != models.GenderMale && gender != models.GenderFemale || weight < 0 {
+			panic(fmt.Errorf("invalid gender probability: %s %v", gender, weight))
+		}
+		total += weight
+	}
+	if len(p.Gender) > 0 && total == 0 {
+		panic(fmt.Errorf("gender probabilities sum up to 0"))
+	}
+
+	for gender, chance := range p.Hair {
+		if gender != models.GenderMale && gender != models.GenderFemale || chance < 0 || chance > 100 {
+			panic(fmt.Errorf("invalid hair probability: %s %v", gender, chance))
+		}
+	}
+}
+
+// AttributeOptions returns the attribute options, completed with the merge
+// policies set in the slot and derived mappings.
+func (c *Config) AttributeOptions() models.AttributeOptions {
+	return c.attributeOptions
+}
+
+// mergeAttributeOptions completes the attribute options with the merge
+// policies of the slot mappings, then of the derived mappings, in list order.
+// A policy set in the attribute options wins; otherwise it panics if two
+// mappings report the same trait type with different policies.
+func mergeAttributeOptions(c *Config) models.AttributeOptions {
+	opts := c.Attributes
+	opts.Merge = make(map[string]models.MergePolicy)
+
+	for traitType, policy := range c.Attributes.Merge {
+		opts.Merge[traitType] = policy
+	}
+
+	owners := make(map[string]string)
+	add := func(mapping models.AttributeMapping, traitType string, owner string) {
+		if mapping.TraitType != "" {
+			traitType = mapping.TraitType
+		}
+		if mapping.Merge == models.MergeKeep || traitType == "" {
+			return
+		}
+		if _, ok := c.Attributes.Merge[traitType]; ok {
+			return
+		}
+		if policy, ok := opts.Merge[traitType]; ok && policy != mapping.Merge {
+			panic(fmt.Errorf("conflicting merge policies for %s: %s for %s, %s for %s",
+				traitType, policy, owners[traitType], mapping.Merge, owner))
+		}
+		opts.Merge[traitType] = mapping.Merge
+		owners[traitType] = owner
+	}
+
+	for _, slot := range models.SlotList() {
+		if mapping, ok := c.Slots[slot]; ok {
+			add(mapping, slot.TraitType(), slot.String())
+		}
+	}
+	for _, derived := range models.DerivedList() {
+		if mapping, ok := c.Derived[derived]; ok {
+			add(mapping, derived.String(), derived.String())
+		}
+	}
+
+	return opts
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"generator/collector"
+	"generator/config"
+	"generator/models"
+	"log"
+	"os"
+	"strconv"
+)
+
+// executeExport converts the generated metadata of the first nrNFTs tokens to
+// the given profile and writes it to output, so the collection can launch on
+// marketplaces and chains expecting another metadata standard.
+func executeExport(nrNFTs int, profile models.Profile, output string) {
+	if profile.IsInvalid() {
+		log.Fatalf("invalid profile: %s", profile)
+	}
+
+	if err := os.MkdirAll(output, 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	opts := config.Get().Metadata
+
+	for tokenID := 0; tokenID < nrNFTs; tokenID++ {
+		metadata, err := collector.GetMetadataWithError(strconv.Itoa(tokenID))
+		if err != nil {
+			log.Printf("File not found: %d: %s", tokenID, err)
+			continue
+		}
+
+		writeToSimpleFile(fmt.Sprintf("%s/%d.json", output, tokenID), metadata.Profiled(profile, opts))
+	}
+}
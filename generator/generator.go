@@ -7,6 +7,7 @@ import (
 	"image/color"
 	"image/draw"
 	"image/png"
+	"io"
 	"log"
 	"math"
 	"os"
@@ -116,23 +117,27 @@ func blendImages(img1, img2 *image.RGBA, params BlendParameters) (*image.RGBA, e
 
 // WriteTo saves the final image to the specified path.
 func (c ImageCreator) WriteTo(outputPath string) {
-	if c.final == nil {
-		log.Fatal(errors.New("final image is nil"))
-	}
-
 	finalImageOutput, err := os.Create(outputPath)
 	if err != nil {
 		log.Fatal(fmt.Errorf("failed to create: %s", err))
 	}
 	defer finalImageOutput.Close()
 
-	// Save the image using imgio's PNG encoder
-	if err := imgio.Save(outputPath, c.final, imgio.PNGEncoder()); err != nil {
+	if err := c.Encode(finalImageOutput); err != nil {
 		fmt.Println(err)
 		return
 	}
 }
 
+// Encode writes the final image to w as a PNG.
+func (c ImageCreator) Encode(w io.Writer) error {
+	if c.final == nil {
+		return errors.New("final image is nil")
+	}
+
+	return imgio.PNGEncoder()(w, c.final)
+}
+
 // getImage loads an image from the specified path.
 func getImage(imagePath string) image.Image {
 	imageSource, err := os.Open(imagePath)
@@ -2,7 +2,10 @@ module generator
 
 go 1.19
 
-require github.com/samber/lo v1.38.1
+require (
+	github.com/samber/lo v1.38.1
+	golang.org/x/crypto v0.5.0
+)
 
 require golang.org/x/image v0.0.0-20190703141733-d6a02ce849c9 // indirect
 
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"generator/ipfs"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// IPFSRecord lists the CIDs of the packed folders.
+type IPFSRecord struct {
+	Images   IPFSRoot `json:"images"`
+	Metadata IPFSRoot `json:"metadata"`
+}
+
+// IPFSRoot is the root of a packed folder, in both CID versions, and the CAR file holding it.
+type IPFSRoot struct {
+	CIDv0 string `json:"cid_v0"`
+	CIDv1 string `json:"cid_v1"`
+	Size  uint64 `json:"size"` // Cumulative size of the DAG.
+	Car   string `json:"car"`
+}
+
+// ipfsBackend builds the UnixFS DAGs of the images and metadata folders
+// offline and writes each one to a CAR file ready to be uploaded to a pinning
+// service. The CIDs are written to cids.json in the output folder.
+type ipfsBackend struct {
+	opts           ipfs.Options // How files are chunked and linked.
+	output         string       // Folder the CAR files and CIDs are written to.
+	allowUnsharded bool         // Accept folders "ipfs add" would shard, whose CIDs then differ from it.
+	record         IPFSRecord
+}
+
+// newIPFSBackend returns an IPFS backend writing to the output folder.
+func newIPFSBackend(opts ipfs.Options, output string, allowUnsharded bool) *ipfsBackend {
+	if err := os.MkdirAll(output, 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	return &ipfsBackend{
+		opts:           opts,
+		output:         output,
+		allowUnsharded: allowUnsharded,
+	}
+}
+
+// PackImages packs the images folder and returns its CID as "{cid}", in the configured version.
+func (b *ipfsBackend) PackImages() (map[string]string, error) {
+	root, err := b.packFolder("./assets/results/images", "images.car")
+	if err != nil {
+		return nil, err
+	}
+	b.record.Images = root
+	log.Printf("Images CID: %s", root.CIDv1)
+
+	if b.opts.CIDVersion == 0 {
+		return map[string]string{"cid": root.CIDv0}, nil
+	}
+	return map[string]string{"cid": root.CIDv1}, nil
+}
+
+// PackMetadata packs the metadata folder and writes the CIDs of both folders.
+func (b *ipfsBackend) PackMetadata() error {
+	root, err := b.packFolder("./assets/results/metadata", "metadata.car")
+	if err != nil {
+		return err
+	}
+	b.record.Metadata = root
+	log.Printf("Metadata CID: %s", root.CIDv1)
+
+	writeToSimpleFile(filepath.Join(b.output, "cids.json"), b.record)
+	return nil
+}
+
+// packFolder writes the DAG of a folder to the named CAR file and returns its
+// root. It fails when a directory reaches the sharding threshold of "ipfs add",
+// unless allowUnsharded accepts a CID "ipfs add" would not give.
+func (b *ipfsBackend) packFolder(folder, name string) (IPFSRoot, error) {
+	output := filepath.Join(b.output, name)
+
+	car, err := ipfs.NewCarWriter(output)
+	if err != nil {
+		return IPFSRoot{}, err
+	}
+
+	builder := ipfs.NewBuilder(b.opts, car)
+	root, err := builder.AddPath(folder)
+	if err != nil {
+		car.Close()
+		return IPFSRoot{}, fmt.Errorf("importing %s: %w", folder, err)
+	}
+
+	if oversized := builder.Oversized(); len(oversized) > 0 {
+		if !b.allowUnsharded {
+			car.Close()
+			return IPFSRoot{}, fmt.Errorf("%v reach the sharding threshold of ipfs add, which would give another CID; "+
+				"pass --allow-unsharded to pack them unsharded anyway", oversized)
+		}
+		for _, dir := range oversized {
+			log.Printf("Warning: %s is packed unsharded, re-importing it with ipfs add gives another CID", dir)
+		}
+	}
+
+	if err := car.Finish(root.CID); err != nil {
+		return IPFSRoot{}, fmt.Errorf("writing %s: %w", output, err)
+	}
+
+	return IPFSRoot{
+		CIDv0: root.CID.V0().String(),
+		CIDv1: root.CID.V1().String(),
+		Size:  root.Size,
+		Car:   output,
+	}, nil
+}
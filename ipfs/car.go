@@ -0,0 +1,136 @@
+package ipfs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CarWriter collects blocks into a CARv1 file. Blocks are spooled to a
+// temporary file next to the output, since the header holds the root CID
+// which is only known once the whole DAG has been built. Identical blocks are
+// only written once.
+type CarWriter struct {
+	path  string
+	spool *os.File
+	buf   *bufio.Writer
+	seen  map[string]struct{}
+}
+
+// NewCarWriter returns a writer producing the CAR file at path.
+func NewCarWriter(path string) (*CarWriter, error) {
+	spool, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &CarWriter{
+		path:  path,
+		spool: spool,
+		buf:   bufio.NewWriter(spool),
+		seen:  make(map[string]struct{}),
+	}, nil
+}
+
+// Put adds a block to the CAR file.
+func (w *CarWriter) Put(c CID, block []byte) error {
+	key := string(c.Bytes())
+	if _, ok := w.seen[key]; ok {
+		return nil
+	}
+	w.seen[key] = struct{}{}
+
+	return writeSection(w.buf, c.Bytes(), block)
+}
+
+// Finish writes the CAR file with the given root, then removes the spool.
+func (w *CarWriter) Finish(root CID) error {
+	defer w.Close()
+
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if _, err := w.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	out, err := os.Create(w.path)
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(out)
+	if err := writeSection(buf, carHeader(root)); err != nil {
+		out.Close()
+		return err
+	}
+	if _, err := io.Copy(buf, w.spool); err != nil {
+		out.Close()
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// Close removes the spool. It is safe to call after Finish.
+func (w *CarWriter) Close() error {
+	w.spool.Close()
+	return os.Remove(w.spool.Name())
+}
+
+// carHeader encodes the DAG-CBOR header {"roots": [root], "version": 1}.
+func carHeader(root CID) []byte {
+	cid := append([]byte{0x00}, root.Bytes()...) // CIDs are tagged byte strings with a leading zero.
+
+	header := []byte{0xa2} // Map of 2 entries, keys sorted by length then bytes.
+	header = appendCBORHead(header, 3, 5)
+	header = append(header, "roots"...)
+	header = append(header, 0x81)       // Array of 1 item.
+	header = append(header, 0xd8, 0x2a) // Tag 42: CID.
+	header = appendCBORHead(header, 2, uint64(len(cid)))
+	header = append(header, cid...)
+	header = appendCBORHead(header, 3, 7)
+	header = append(header, "version"...)
+	return append(header, 0x01)
+}
+
+// appendCBORHead appends the head of a CBOR item of the given major type and argument.
+func appendCBORHead(buf []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= 0xff:
+		return append(buf, major|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(buf, major|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(buf, major|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major|27), n)
+	}
+}
+
+// writeSection writes a varint-prefixed section made of the given parts.
+func writeSection(w io.Writer, parts ...[]byte) error {
+	var length int
+	for _, part := range parts {
+		length += len(part)
+	}
+
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(length))); err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package ipfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readSection reads a varint-prefixed section of a CAR file.
+func readSection(t *testing.T, buf []byte) (section, rest []byte) {
+	t.Helper()
+
+	length, n := binary.Uvarint(buf)
+	if n <= 0 || uint64(len(buf)-n) < length {
+		t.Fatal("truncated section")
+	}
+	return buf[n : n+int(length)], buf[n+int(length):]
+}
+
+// splitCID splits the CID at the start of a section from its block.
+func splitCID(t *testing.T, section []byte) (cid, block []byte) {
+	t.Helper()
+
+	if section[0] == sha256Code {
+		return section[:2+sha256Length], section[2+sha256Length:]
+	}
+
+	_, n := binary.Uvarint(section) // Version.
+	_, m := binary.Uvarint(section[n:])
+	length := n + m + 2 + sha256Length
+	return section[:length], section[length:]
+}
+
+func TestCarWriter(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"0.json": "hello world\n", "1.json": "hello world\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "metadata.car")
+	car, err := NewCarWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := memorySink{}
+	root, err := NewBuilder(DefaultOptions(1), teeSink{car, blocks}).AddPath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := car.Finish(root.CID); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// {"roots": [root], "version": 1} in DAG-CBOR.
+	cid := append([]byte{0x00}, root.CID.Bytes()...)
+	expected := []byte{0xa2, 0x65}
+	expected = append(expected, "roots"...)
+	expected = append(expected, 0x81, 0xd8, 0x2a, 0x58, byte(len(cid)))
+	expected = append(expected, cid...)
+	expected = append(expected, 0x67)
+	expected = append(expected, "version"...)
+	expected = append(expected, 0x01)
+
+	header, rest := readSection(t, data)
+	if !bytes.Equal(header, expected) {
+		t.Errorf("header = %x\nexpected %x", header, expected)
+	}
+
+	// Both files have the same content, so its block is written once.
+	read := make(map[string]bool)
+	for len(rest) > 0 {
+		var section []byte
+		section, rest = readSection(t, rest)
+		cid, block := splitCID(t, section)
+
+		if read[string(cid)] {
+			t.Errorf("block %x written twice", cid)
+		}
+		read[string(cid)] = true
+
+		if digest := sha256.Sum256(block); !bytes.Equal(cid[len(cid)-sha256Length:], digest[:]) {
+			t.Errorf("block %x does not match its CID", cid)
+		}
+		if !bytes.Equal(blocks[string(cid)], block) {
+			t.Errorf("block %x differs from the one built", cid)
+		}
+	}
+	if len(read) != len(blocks) {
+		t.Errorf("read %d blocks, expected %d", len(read), len(blocks))
+	}
+
+	// The spool is removed.
+	if matches, _ := filepath.Glob(path + ".*.tmp"); len(matches) > 0 {
+		t.Errorf("spool left behind: %v", matches)
+	}
+}
+
+// teeSink hands every block to two sinks.
+type teeSink struct {
+	a, b BlockSink
+}
+
+func (s teeSink) Put(c CID, block []byte) error {
+	if err := s.a.Put(c, block); err != nil {
+		return err
+	}
+	return s.b.Put(c, block)
+}
@@ -0,0 +1,115 @@
+package ipfs
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"math/big"
+)
+
+// Multicodec codes of the block formats.
+const (
+	CodecRaw   uint64 = 0x55 // Raw bytes, used by raw leaves
+	CodecDagPB uint64 = 0x70 // dag-pb, used by every UnixFS node
+)
+
+// Multihash code and length of SHA2-256.
+const (
+	sha256Code   = 0x12
+	sha256Length = 32
+)
+
+// base58Alphabet is the Bitcoin alphabet used by CIDv0.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base32Encoding is the lower-case, unpadded RFC 4648 alphabet used by CIDv1 ("b" multibase).
+var base32Encoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// CID is a content identifier: the version, the codec of the block and the
+// SHA2-256 multihash of its bytes.
+type CID struct {
+	Version   int
+	Codec     uint64
+	Multihash []byte
+}
+
+// NewCID hashes a block and returns its CID. Version 0 is only possible for dag-pb blocks.
+func NewCID(version int, codec uint64, block []byte) CID {
+	digest := sha256.Sum256(block)
+
+	multihash := make([]byte, 0, 2+sha256Length)
+	multihash = append(multihash, sha256Code, sha256Length)
+	multihash = append(multihash, digest[:]...)
+
+	if codec != CodecDagPB {
+		version = 1
+	}
+
+	return CID{
+		Version:   version,
+		Codec:     codec,
+		Multihash: multihash,
+	}
+}
+
+// Bytes returns the binary form of the CID, as stored in links and CAR files.
+func (c CID) Bytes() []byte {
+	if c.Version == 0 {
+		return c.Multihash
+	}
+
+	buf := make([]byte, 0, 2*binary.MaxVarintLen64+len(c.Multihash))
+	buf = binary.AppendUvarint(buf, 1)
+	buf = binary.AppendUvarint(buf, c.Codec)
+	return append(buf, c.Multihash...)
+}
+
+// String returns the CID in base58btc for version 0, and in base32 for version 1.
+func (c CID) String() string {
+	if c.Version == 0 {
+		return base58Encode(c.Multihash)
+	}
+	return "b" + base32Encoding.EncodeToString(c.Bytes())
+}
+
+// V0 returns the CID as version 0. It only applies to dag-pb CIDs; others are returned unchanged.
+func (c CID) V0() CID {
+	if c.Codec == CodecDagPB {
+		c.Version = 0
+	}
+	return c
+}
+
+// V1 returns the CID as version 1.
+func (c CID) V1() CID {
+	c.Version = 1
+	return c
+}
+
+// base58Encode encodes bytes in base58btc, keeping leading zero bytes as "1".
+func base58Encode(data []byte) string {
+	var result []byte
+
+	value := new(big.Int).SetBytes(data)
+	base := big.NewInt(58)
+	mod := new(big.Int)
+
+	for value.Sign() > 0 {
+		value.DivMod(value, base, mod)
+		result = append(result, base58Alphabet[mod.Int64()])
+	}
+
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		result = append(result, base58Alphabet[0])
+	}
+
+	// Reverse the digits, which were produced least significant first.
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return string(result)
+}
@@ -0,0 +1,45 @@
+package ipfs
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCIDVersions(t *testing.T) {
+	// The empty directory node.
+	c := NewCID(0, CodecDagPB, []byte{0x0a, 0x02, 0x08, 0x01})
+
+	if got := c.String(); got != "QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn" {
+		t.Errorf("CIDv0 = %s, expected QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn", got)
+	}
+	if got := c.V1().String(); got != "bafybeiczsscdsbs7ffqz55asqdf3smv6klcw3gofszvwlyarci47bgf354" {
+		t.Errorf("CIDv1 = %s, expected bafybeiczsscdsbs7ffqz55asqdf3smv6klcw3gofszvwlyarci47bgf354", got)
+	}
+	if got := c.V1().V0(); got.String() != c.String() {
+		t.Errorf("V1().V0() = %s, expected %s", got, c)
+	}
+
+	if !bytes.Equal(c.Bytes(), c.Multihash) {
+		t.Error("a CIDv0 is not its multihash")
+	}
+	if got := c.V1().Bytes(); !bytes.Equal(got[:2], []byte{0x01, 0x70}) || !bytes.Equal(got[2:], c.Multihash) {
+		t.Errorf("CIDv1 bytes = %x", got)
+	}
+}
+
+func TestRawCIDIsVersion1(t *testing.T) {
+	c := NewCID(0, CodecRaw, []byte("hello world\n"))
+
+	if c.Version != 1 {
+		t.Errorf("version = %d, expected 1", c.Version)
+	}
+	if got := c.V0().String(); got != "bafkreifjjcie6lypi6ny7amxnfftagclbuxndqonfipmb64f2km2devei4" {
+		t.Errorf("V0() = %s, expected the raw CIDv1 unchanged", got)
+	}
+}
+
+func TestBase58LeadingZeros(t *testing.T) {
+	if got := base58Encode([]byte{0, 0, 1}); got != "112" {
+		t.Errorf("base58Encode(0 0 1) = %s, expected 112", got)
+	}
+}
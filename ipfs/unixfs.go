@@ -0,0 +1,321 @@
+package ipfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Defaults of the importer, matching those of "ipfs add".
+const (
+	DefaultChunkSize = 262144 // Size of the chunks files are split into
+	DefaultMaxLinks  = 174    // Maximum number of links of a node in the balanced layout
+)
+
+// ShardingThreshold is the estimated size from which "ipfs add" stores a
+// directory as a HAMT shard instead of a single node. The estimate is the sum
+// of the lengths of the names and CIDs of the entries. This importer always
+// writes a single node, so a larger directory gets another CID than with "ipfs add".
+const ShardingThreshold = 262144
+
+// UnixFS node types.
+const (
+	unixfsDirectory = 1
+	unixfsFile      = 2
+)
+
+// Options configures how files are imported into a DAG.
+type Options struct {
+	CIDVersion int  // 0 or 1.
+	ChunkSize  int  // Size of the chunks files are split into.
+	MaxLinks   int  // Maximum number of links of a node.
+	RawLeaves  bool // Store file chunks as raw blocks instead of dag-pb nodes. Implies CIDv1 leaves.
+}
+
+// DefaultOptions returns the options of "ipfs add" for the given CID version:
+// 256KiB chunks, a balanced layout of 174 links per node, and raw leaves with
+// CIDv1. The CIDs only match those of "ipfs add" as long as no directory
+// reaches ShardingThreshold, as directories are never sharded.
+func DefaultOptions(cidVersion int) Options {
+	return Options{
+		CIDVersion: cidVersion,
+		ChunkSize:  DefaultChunkSize,
+		MaxLinks:   DefaultMaxLinks,
+		RawLeaves:  cidVersion == 1,
+	}
+}
+
+// ParseChunker parses a chunker in the "size-{bytes}" form of "ipfs add".
+func ParseChunker(chunker string) (int, error) {
+	if !strings.HasPrefix(chunker, "size-") {
+		return 0, fmt.Errorf("unsupported chunker: %s", chunker)
+	}
+
+	size, err := strconv.Atoi(strings.TrimPrefix(chunker, "size-"))
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid chunk size: %s", chunker)
+	}
+
+	return size, nil
+}
+
+// Link points to an imported DAG.
+type Link struct {
+	Name     string // Name of the entry in its directory.
+	CID      CID    // CID of the root block.
+	Size     uint64 // Cumulative size of every block of the DAG.
+	FileSize uint64 // Size of the file content; zero for directories.
+}
+
+// BlockSink receives every block of the imported DAGs.
+type BlockSink interface {
+	Put(c CID, block []byte) error
+}
+
+// Builder imports files and directories into UnixFS DAGs. Blocks are handed
+// to the sink as soon as they are built, so files are never held in memory
+// whole; a nil sink only computes the CIDs.
+type Builder struct {
+	opts      Options
+	sink      BlockSink
+	oversized []string // Directories imported at or past ShardingThreshold.
+}
+
+// NewBuilder returns a builder using the given options and sink.
+func NewBuilder(opts Options, sink BlockSink) *Builder {
+	if opts.CIDVersion != 0 && opts.CIDVersion != 1 {
+		panic(fmt.Sprintf("invalid CID version: %d", opts.CIDVersion))
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.MaxLinks < 2 {
+		opts.MaxLinks = DefaultMaxLinks
+	}
+
+	return &Builder{
+		opts: opts,
+		sink: sink,
+	}
+}
+
+// AddPath imports a file, or a directory with everything it contains.
+// Hidden entries are skipped, as "ipfs add" does.
+func (b *Builder) AddPath(path string) (Link, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Link{}, err
+	}
+
+	if !info.IsDir() {
+		file, err := os.Open(path)
+		if err != nil {
+			return Link{}, err
+		}
+		defer file.Close()
+
+		link, err := b.AddFile(file)
+		link.Name = filepath.Base(path)
+		return link, err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return Link{}, err
+	}
+
+	links := make([]Link, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		link, err := b.AddPath(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return Link{}, err
+		}
+		links = append(links, link)
+	}
+
+	if DirectorySize(links) >= ShardingThreshold {
+		b.oversized = append(b.oversized, path)
+	}
+
+	link, err := b.AddDirectory(links)
+	link.Name = filepath.Base(path)
+	return link, err
+}
+
+// Oversized returns the directories imported by AddPath whose size reaches
+// ShardingThreshold, and whose CID therefore differs from that of "ipfs add".
+func (b *Builder) Oversized() []string {
+	return b.oversized
+}
+
+// DirectorySize estimates the size of a directory the way "ipfs add" does
+// to decide whether to shard it.
+func DirectorySize(entries []Link) int {
+	size := 0
+	for _, entry := range entries {
+		size += len(entry.Name) + len(entry.CID.Bytes())
+	}
+	return size
+}
+
+// AddFile imports the content of r as a file split into fixed-size chunks
+// laid out as a balanced tree.
+func (b *Builder) AddFile(r io.Reader) (Link, error) {
+	var leaves []Link
+
+	chunk := make([]byte, b.opts.ChunkSize)
+	for {
+		n, err := io.ReadFull(r, chunk)
+		if err == io.EOF && len(leaves) > 0 {
+			break
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return Link{}, err
+		}
+
+		leaf, putErr := b.addLeaf(chunk[:n])
+		if putErr != nil {
+			return Link{}, putErr
+		}
+		leaves = append(leaves, leaf)
+
+		if err != nil {
+			break
+		}
+	}
+
+	if len(leaves) == 1 {
+		return leaves[0], nil
+	}
+
+	// Each node at depth d holds up to MaxLinks^d leaves, filled from the left.
+	depth, capacity := 1, b.opts.MaxLinks
+	for capacity < len(leaves) {
+		depth++
+		capacity *= b.opts.MaxLinks
+	}
+
+	return b.addFileNode(leaves, depth)
+}
+
+// addLeaf stores a chunk of a file.
+func (b *Builder) addLeaf(data []byte) (Link, error) {
+	if b.opts.RawLeaves {
+		c := NewCID(1, CodecRaw, data)
+		return Link{CID: c, Size: uint64(len(data)), FileSize: uint64(len(data))}, b.put(c, data)
+	}
+
+	unixfs := appendVarintField(nil, 1, unixfsFile)
+	if len(data) > 0 {
+		unixfs = appendBytesField(unixfs, 2, data)
+	}
+	unixfs = appendVarintField(unixfs, 3, uint64(len(data)))
+
+	return b.addNode(nil, unixfs, uint64(len(data)))
+}
+
+// addFileNode builds the subtree of the given depth linking to the leaves.
+func (b *Builder) addFileNode(leaves []Link, depth int) (Link, error) {
+	children := leaves
+	if depth > 1 {
+		perChild := 1
+		for i := 1; i < depth; i++ {
+			perChild *= b.opts.MaxLinks
+		}
+
+		children = nil
+		for start := 0; start < len(leaves); start += perChild {
+			end := start + perChild
+			if end > len(leaves) {
+				end = len(leaves)
+			}
+
+			child, err := b.addFileNode(leaves[start:end], depth-1)
+			if err != nil {
+				return Link{}, err
+			}
+			children = append(children, child)
+		}
+	}
+
+	var fileSize uint64
+	for _, child := range children {
+		fileSize += child.FileSize
+	}
+
+	unixfs := appendVarintField(nil, 1, unixfsFile)
+	unixfs = appendVarintField(unixfs, 3, fileSize)
+	for _, child := range children {
+		unixfs = appendVarintField(unixfs, 4, child.FileSize)
+	}
+
+	return b.addNode(children, unixfs, fileSize)
+}
+
+// AddDirectory builds a directory node linking to the given entries, which
+// must have unique names.
+func (b *Builder) AddDirectory(entries []Link) (Link, error) {
+	links := append([]Link(nil), entries...)
+	sort.Slice(links, func(i, j int) bool { return links[i].Name < links[j].Name })
+
+	for i := 1; i < len(links); i++ {
+		if links[i].Name == links[i-1].Name {
+			return Link{}, fmt.Errorf("duplicate directory entry: %s", links[i].Name)
+		}
+	}
+
+	return b.addNode(links, appendVarintField(nil, 1, unixfsDirectory), 0)
+}
+
+// addNode encodes a dag-pb node and stores it. Links come first and data
+// last, as the canonical dag-pb encoding requires.
+func (b *Builder) addNode(links []Link, unixfs []byte, fileSize uint64) (Link, error) {
+	var node []byte
+	size := uint64(0)
+
+	for _, link := range links {
+		var pbLink []byte
+		pbLink = appendBytesField(pbLink, 1, link.CID.Bytes())
+		pbLink = appendBytesField(pbLink, 2, []byte(link.Name))
+		pbLink = appendVarintField(pbLink, 3, link.Size)
+
+		node = appendBytesField(node, 2, pbLink)
+		size += link.Size
+	}
+	node = appendBytesField(node, 1, unixfs)
+
+	c := NewCID(b.opts.CIDVersion, CodecDagPB, node)
+	size += uint64(len(node))
+
+	return Link{CID: c, Size: size, FileSize: fileSize}, b.put(c, node)
+}
+
+// put hands a block to the sink, if any.
+func (b *Builder) put(c CID, block []byte) error {
+	if b.sink == nil {
+		return nil
+	}
+	return b.sink.Put(c, block)
+}
+
+// appendVarintField appends a protobuf varint field.
+func appendVarintField(buf []byte, field int, value uint64) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field<<3))
+	return binary.AppendUvarint(buf, value)
+}
+
+// appendBytesField appends a protobuf length-delimited field.
+func appendBytesField(buf []byte, field int, value []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field<<3|2))
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
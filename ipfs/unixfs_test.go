@@ -0,0 +1,306 @@
+package ipfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memorySink keeps the blocks of the imported DAGs, by binary CID.
+type memorySink map[string][]byte
+
+// Put checks that the CID is the hash of the block, and keeps it.
+func (m memorySink) Put(c CID, block []byte) error {
+	if digest := sha256.Sum256(block); !bytes.Equal(c.Multihash[2:], digest[:]) {
+		panic("CID does not match the block")
+	}
+	m[string(c.Bytes())] = append([]byte(nil), block...)
+	return nil
+}
+
+// pbNode is a decoded dag-pb node.
+type pbNode struct {
+	links  []pbLink
+	unixfs []byte
+}
+
+// pbLink is a decoded dag-pb link.
+type pbLink struct {
+	cid  []byte
+	name string
+	size uint64
+}
+
+// pbField is a decoded protobuf field.
+type pbField struct {
+	number int
+	value  uint64 // Value of a varint field.
+	bytes  []byte // Value of a length-delimited field.
+}
+
+// decodeFields decodes the varint and length-delimited fields of a protobuf message.
+func decodeFields(t *testing.T, buf []byte) []pbField {
+	t.Helper()
+
+	var fields []pbField
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 {
+			t.Fatal("invalid field key")
+		}
+		buf = buf[n:]
+
+		value, n := binary.Uvarint(buf)
+		if n <= 0 {
+			t.Fatal("invalid field value")
+		}
+		buf = buf[n:]
+
+		field := pbField{number: int(key >> 3), value: value}
+		switch key & 7 {
+		case 0:
+		case 2:
+			field.bytes, buf = buf[:value], buf[value:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// decodeNode decodes a dag-pb node.
+func decodeNode(t *testing.T, block []byte) pbNode {
+	t.Helper()
+
+	var node pbNode
+	for _, field := range decodeFields(t, block) {
+		switch field.number {
+		case 1:
+			node.unixfs = field.bytes
+		case 2:
+			var link pbLink
+			for _, f := range decodeFields(t, field.bytes) {
+				switch f.number {
+				case 1:
+					link.cid = f.bytes
+				case 2:
+					link.name = string(f.bytes)
+				case 3:
+					link.size = f.value
+				}
+			}
+			node.links = append(node.links, link)
+		}
+	}
+	return node
+}
+
+// readFile reassembles the content of a file DAG, and returns the number of
+// links of every node by depth.
+func readFile(t *testing.T, blocks memorySink, cid []byte, depth int, fanout map[int][]int) []byte {
+	t.Helper()
+
+	block, ok := blocks[string(cid)]
+	if !ok {
+		t.Fatalf("missing block %x", cid)
+	}
+	if len(cid) > 2 && cid[0] == 1 && cid[1] == byte(CodecRaw) {
+		return block
+	}
+
+	node := decodeNode(t, block)
+	fanout[depth] = append(fanout[depth], len(node.links))
+	if len(node.links) == 0 {
+		for _, field := range decodeFields(t, node.unixfs) {
+			if field.number == 2 {
+				return field.bytes
+			}
+		}
+		return nil
+	}
+
+	var content []byte
+	for _, link := range node.links {
+		content = append(content, readFile(t, blocks, link.cid, depth+1, fanout)...)
+	}
+	return content
+}
+
+func TestAddFileHelloWorld(t *testing.T) {
+	link, err := NewBuilder(DefaultOptions(0), nil).AddFile(strings.NewReader("hello world\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := link.CID.String(); got != "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o" {
+		t.Errorf("CID = %s, expected QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o", got)
+	}
+	if link.FileSize != 12 {
+		t.Errorf("file size = %d, expected 12", link.FileSize)
+	}
+}
+
+func TestAddFileRawLeaf(t *testing.T) {
+	link, err := NewBuilder(DefaultOptions(1), nil).AddFile(strings.NewReader("hello world\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A single chunk is stored as is, as a raw block.
+	if got := link.CID.String(); got != "bafkreifjjcie6lypi6ny7amxnfftagclbuxndqonfipmb64f2km2devei4" {
+		t.Errorf("CID = %s, expected bafkreifjjcie6lypi6ny7amxnfftagclbuxndqonfipmb64f2km2devei4", got)
+	}
+	if link.Size != 12 {
+		t.Errorf("size = %d, expected 12", link.Size)
+	}
+}
+
+func TestAddDirectoryEmpty(t *testing.T) {
+	link, err := NewBuilder(DefaultOptions(0), nil).AddPath(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := link.CID.String(); got != "QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn" {
+		t.Errorf("CID = %s, expected QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn", got)
+	}
+	if link.Size != 4 {
+		t.Errorf("size = %d, expected 4", link.Size)
+	}
+}
+
+func TestAddDirectoryLinks(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"1.json": "{}", "0.json": "hello world\n", ".hidden": "skipped"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	blocks := memorySink{}
+	link, err := NewBuilder(DefaultOptions(0), blocks).AddPath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := decodeNode(t, blocks[string(link.CID.Bytes())])
+	if len(node.links) != 2 || node.links[0].name != "0.json" || node.links[1].name != "1.json" {
+		t.Fatalf("links = %+v, expected 0.json and 1.json", node.links)
+	}
+
+	hello := base58Encode(node.links[0].cid)
+	if hello != "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o" {
+		t.Errorf("0.json links to %s, expected QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o", hello)
+	}
+
+	// The cumulative size is the sum of the sizes of every block.
+	var size uint64
+	for _, block := range blocks {
+		size += uint64(len(block))
+	}
+	if link.Size != size {
+		t.Errorf("size = %d, expected %d", link.Size, size)
+	}
+}
+
+// patternReader produces n bytes of a repeating pattern, so every chunk differs from the next.
+type patternReader struct {
+	n, pos int
+}
+
+func (p *patternReader) Read(buf []byte) (int, error) {
+	if p.pos >= p.n {
+		return 0, io.EOF
+	}
+	if len(buf) > p.n-p.pos {
+		buf = buf[:p.n-p.pos]
+	}
+	for i := range buf {
+		buf[i] = byte((p.pos + i) % 251)
+	}
+	p.pos += len(buf)
+	return len(buf), nil
+}
+
+func TestAddFileBalancedTree(t *testing.T) {
+	// One chunk more than a single node can link to.
+	size := DefaultMaxLinks*DefaultChunkSize + 1000
+
+	for _, cidVersion := range []int{0, 1} {
+		blocks := memorySink{}
+		link, err := NewBuilder(DefaultOptions(cidVersion), blocks).AddFile(&patternReader{n: size})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if link.FileSize != uint64(size) {
+			t.Errorf("CIDv%d: file size = %d, expected %d", cidVersion, link.FileSize, size)
+		}
+
+		fanout := make(map[int][]int)
+		content := readFile(t, blocks, link.CID.Bytes(), 0, fanout)
+		if !bytes.Equal(content, mustRead(t, &patternReader{n: size})) {
+			t.Errorf("CIDv%d: the DAG does not hold the file content", cidVersion)
+		}
+
+		// The root links to a full node of 174 leaves and a node of the last one.
+		if got := fanout[0]; len(got) != 1 || got[0] != 2 {
+			t.Errorf("CIDv%d: root links = %v, expected [2]", cidVersion, got)
+		}
+		if got := fanout[1]; len(got) != 2 || got[0] != DefaultMaxLinks || got[1] != 1 {
+			t.Errorf("CIDv%d: second level links = %v, expected [%d 1]", cidVersion, got, DefaultMaxLinks)
+		}
+	}
+}
+
+// mustRead reads everything from r.
+func mustRead(t *testing.T, r io.Reader) []byte {
+	t.Helper()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseChunker(t *testing.T) {
+	if size, err := ParseChunker("size-1024"); err != nil || size != 1024 {
+		t.Errorf("ParseChunker(size-1024) = %d, %v", size, err)
+	}
+	for _, chunker := range []string{"rabin", "size-0", "size-x"} {
+		if _, err := ParseChunker(chunker); err == nil {
+			t.Errorf("ParseChunker(%s) did not fail", chunker)
+		}
+	}
+}
+
+func TestShardingThreshold(t *testing.T) {
+	// Every entry has a 30 byte name and a 34 byte CIDv0: 64 bytes.
+	entries := ShardingThreshold / 64
+
+	for _, count := range []int{entries - 1, entries} {
+		dir := t.TempDir()
+		for i := 0; i < count; i++ {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%030d", i)), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		b := NewBuilder(DefaultOptions(0), nil)
+		if _, err := b.AddPath(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		if oversized := len(b.Oversized()) > 0; oversized != (count == entries) {
+			t.Errorf("%d entries: oversized = %v", count, oversized)
+		}
+	}
+}
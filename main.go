@@ -2,261 +2,523 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"generator/collector"
-	"generator/generator"
+	"generator/config"
+	"generator/ipfs"
 	"generator/models"
 	"generator/parse"
 	"generator/processor"
 	"generator/utils"
 	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
-	"strings"
 	"sync"
 
 	"github.com/google/uuid"
 )
 
 var (
-	baseFolder = "./assets/traits/"
-	maxWorkers = 15
-	max_NFTS   = 7573
+	baseFolder       = "./assets/traits/"
+	compositeWorkers = runtime.NumCPU()
+	encodeWorkers    = 4
+	queueSize        = 64
+	max_NFTS         = 7573
 )
 
 var (
 	mu        sync.Mutex
-	muRar     sync.Mutex
 	m         = make(map[string]struct{})
 	responses = collector.GetResponses()
-	rarities  = make(map[string]map[string]int)
-	ram       = make(chan TraitData, 1000)
 )
 
 func main() {
-	executeSingle(1)
+	if len(os.Args) < 2 {
+		executeSingle(1)
+		return
+	}
 
-	// executeCollection(7573)
+	command, args := os.Args[1], os.Args[2:]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
 
-	// replaceImageURLs(7573)
-}
+	switch command {
+	case "single":
+		tokenID := flags.Int("token", 1, "token to generate")
+		flags.Parse(args)
 
-func replaceImageURLs(nrNFTs int) {
-	for tokenID := 0; tokenID < nrNFTs; tokenID++ {
+		executeSingle(*tokenID)
+	case "generate":
+		count := flags.Int("count", max_NFTS, "number of tokens to generate")
+		dryRun := flags.Bool("dry-run", false, "only plan the trait assignments, without rendering")
+		flags.Parse(args)
 
-		metadata, err := collector.GetMetadataWithError(strconv.Itoa(tokenID))
+		if *dryRun {
+			executePlan(*count, planFile)
+		} else {
+			executeCollection(*count)
+		}
+	case "plan":
+		count := flags.Int("count", max_NFTS, "number of tokens to plan")
+		output := flags.String("out", planFile, "plan file to write")
+		flags.Parse(args)
+
+		executePlan(*count, *output)
+	case "analyze":
+		all := flags.Bool("all", false, "also analyze the segments without any token in the collection")
+		flags.Parse(args)
+
+		executeAnalyze(*all)
+	case "audit":
+		count := flags.Int("count", max_NFTS, "number of tokens to audit")
+		planName := flags.String("plan", manifestFile, "plan or manifest recording the layers of every token")
+		flags.Parse(args)
+
+		executeAudit(*count, *planName)
+	case "render":
+		planName := flags.String("plan", planFile, "plan file listing the layers of every token")
+		force := flags.Bool("force", false, "render tokens that break the compatibility rules")
+		flags.Parse(args)
+
+		executeRender(*planName, *force)
+	case "export":
+		count := flags.Int("count", max_NFTS, "number of metadata files to export")
+		profile := flags.String("profile", config.Get().Profile.String(), "metadata profile: default, opensea, erc721 or metaplex")
+		output := flags.String("out", "", "folder to write to (defaults to ./assets/results/metadata_{profile})")
+		flags.Parse(args)
+
+		if *output == "" {
+			*output = "./assets/results/metadata_" + *profile
+		}
+
+		executeExport(*count, models.Profile(*profile), *output)
+	case "provenance":
+		count := flags.Int("count", max_NFTS, "number of tokens in the collection")
+		blockHash := flags.String("block-hash", "", "block hash to derive the starting index from")
+		output := flags.String("out", "./assets/results/provenance.json", "provenance table to write")
+		flags.Parse(args)
+
+		executeProvenance(*count, *blockHash, *output)
+	case "shuffle":
+		count := flags.Int("count", max_NFTS, "number of tokens in the collection")
+		seed := flags.String("seed", "", "seed of a Fisher–Yates shuffle")
+		offset := flags.Int("offset", 0, "offset added to every token ID when no seed is given")
+		blockHash := flags.String("block-hash", "", "block hash to derive the offset from when no seed is given")
+		flags.Parse(args)
+
+		executeShuffle(*count, *seed, *offset, *blockHash)
+	case "placeholder":
+		count := flags.Int("count", max_NFTS, "number of tokens in the collection")
+		output := flags.String("out", "./assets/results/placeholder", "folder to write the placeholder metadata to")
+		flags.Parse(args)
+
+		executePlaceholder(*count, *output)
+	case "rewrite-uris":
+		count := flags.Int("count", max_NFTS, "number of metadata files to rewrite")
+		image := flags.String("image", "", `template of the image URI, such as "ipfs://{cid}/{id}.png"`)
+		animation := flags.String("animation", "", "template of the animation URI")
+		external := flags.String("external", "", "template of the external URL")
+		cid := flags.String("cid", "", `value of "{cid}" in the templates`)
+		form := flags.String("form", "", "form of the IPFS URIs: native or gateway (default: as templated)")
+		gateway := flags.String("gateway", "https://ipfs.io", "IPFS gateway of the gateway form")
+		allowMissing := flags.Bool("allow-missing", false, "rewrite tokens whose files are missing locally")
+		flags.Parse(args)
+
+		executeRewriteURIs(*count, RewriteOptions{
+			Image:        *image,
+			Animation:    *animation,
+			External:     *external,
+			Params:       map[string]string{"cid": *cid},
+			Form:         URIForm(*form),
+			Gateway:      *gateway,
+			AllowMissing: *allowMissing,
+		})
+	case "ipfs":
+		cidVersion := flags.Int("cid-version", 1, "CID version: 0 or 1")
+		chunker := flags.String("chunker", "size-262144", `chunker, in the "size-{bytes}" form`)
+		rawLeaves := flags.Bool("raw-leaves", false, "store file chunks as raw blocks (default: true with CIDv1)")
+		output := flags.String("out", "./assets/results/car", "folder to write the CAR files and CIDs to")
+		count := flags.Int("count", max_NFTS, "number of metadata files to rewrite")
+		image := flags.String("image", "ipfs://{cid}/{id}.png", "template of the image URI; empty skips the rewrite")
+		form := flags.String("form", "", "form of the IPFS URIs: native or gateway (default: as templated)")
+		gateway := flags.String("gateway", "https://ipfs.io", "IPFS gateway of the gateway form")
+		allowUnsharded := flags.Bool("allow-unsharded", false, "pack folders ipfs add would shard, with CIDs ipfs add does not give")
+		flags.Parse(args)
+
+		chunkSize, err := ipfs.ParseChunker(*chunker)
 		if err != nil {
-			log.Printf("File not found: %d: %s", tokenID, err)
-			continue
+			log.Fatal(err)
 		}
 
-		metadata.Image = strings.ReplaceAll(metadata.Image, "REPLACE_ME", "bafybeibh3auum3psmutucg52tlmdj4zkdyqkvlzta43k76mvgpkr72otby")
+		opts := ipfs.DefaultOptions(*cidVersion)
+		opts.ChunkSize = chunkSize
+		flags.Visit(func(f *flag.Flag) {
+			if f.Name == "raw-leaves" {
+				opts.RawLeaves = *rawLeaves
+			}
+		})
+
+		publish(newIPFSBackend(opts, *output, *allowUnsharded), *count, RewriteOptions{
+			Image:   *image,
+			Form:    URIForm(*form),
+			Gateway: *gateway,
+		})
+	case "arweave":
+		mapping := flags.String("mapping", "", "JSON file mapping paths such as \"0.png\" to transaction IDs")
+		output := flags.String("out", "./assets/results/arweave/manifest.json", "manifest file to write")
+		index := flags.String("index", "", "path served at the root of the manifest")
+		manifestID := flags.String("manifest-id", "", "transaction ID of the uploaded manifest, to rewrite the metadata with")
+		count := flags.Int("count", max_NFTS, "number of tokens in the collection")
+		image := flags.String("image", "ar://{manifest}/{id}.png", "template of the image URI; empty skips the rewrite")
+		flags.Parse(args)
+
+		if *mapping == "" {
+			log.Fatal("--mapping is required")
+		}
 
-		writeToSimpleFile(fmt.Sprintf("./assets/results/metadata/%d.json", tokenID), metadata)
+		publish(&arweaveBackend{
+			mapping:    *mapping,
+			output:     *output,
+			index:      *index,
+			manifestID: *manifestID,
+			nrNFTs:     *count,
+		}, *count, RewriteOptions{Image: *image})
+	default:
+		log.Fatalf("unknown command %q", command)
 	}
 }
 
 func executeSingle(tokenID int) {
-	maxWorkers = 1
+	compositeWorkers = 1
+	encodeWorkers = 1
 
 	seed := uuid.NewString()
+	kind := config.Get().RandomSource
 
 	mm, err := collector.GetMetadataWithError(strconv.Itoa(tokenID))
-	if err == nil {
-		seed = mm.Seed
-	} else {
+	if err != nil {
 		log.Println("Error getting metadata: ", err)
+	} else if mm.Seed != "" {
+		seed, kind = mm.Seed, replaySource(tokenID, mm.Seed)
+		log.Printf("Replaying token %d with the %s source", tokenID, kind)
 	}
 
 	tr := parse.Do()
-	r := utils.NewRandomizer(seed)
+	r := utils.NewRandomizerWith(kind, seed, tokenID)
+
+	spec, err := processToken(r, tr.Copy(), tokenID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	specs := make(chan TokenSpec, 1)
+	specs <- spec
+	close(specs)
 
-	var wg sync.WaitGroup
-	workers := make(chan struct{}, maxWorkers)
-	workers <- struct{}{}
-	wg.Add(1)
+	results := openResults()
+	runPipeline(specs, results)
+	closeResults(results)
+}
 
-	go processToken(&wg, workers, r, tr.Copy(), tokenID)
+// replaySource returns the random source a token was generated with, as
+// recorded in the manifest of the run that produced the given seed. A token
+// without a recorded source comes from a run older than the recorded sources,
+// which drew with the original sampler: it is replayed with SourceSHA1Legacy.
+func replaySource(tokenID int, seed string) utils.SourceKind {
+	specs, err := readPlan(manifestFile)
+	if err != nil {
+		return utils.SourceSHA1Legacy
+	}
+
+	for _, spec := range specs {
+		if spec.TokenID == tokenID && spec.Metadata != nil && spec.Metadata.Seed == seed && spec.Source != "" {
+			return spec.Source
+		}
+	}
 
-	wg.Wait()
+	return utils.SourceSHA1Legacy
 }
 
 func executeCollection(nrNFTs int) {
 	tr := parse.Do()
 
+	if nrNFTs > max_NFTS {
+		nrNFTs = max_NFTS
+	}
+
+	// Selection runs on a single goroutine so tokens are picked, and checked
+	// for uniqueness, in token order on every run.
+	specs := make(chan TokenSpec, queueSize)
+	manifest := make([]TokenSpec, 0, nrNFTs)
 	go func() {
-		for {
-			select {
-			case data := <-ram:
-				muRar.Lock()
-				if rarities[data.Folder] == nil {
-					rarities[data.Folder] = make(map[string]int)
-				}
-				rarities[data.Folder][data.Name]++
-				muRar.Unlock()
-				writeToSimpleFile("./assets/results/rarity.json", rarities)
+		defer close(specs)
+
+		for tokenID := 0; tokenID < nrNFTs; tokenID++ {
+			spec, err := processToken(nil, tr.Copy(), tokenID)
+			if err != nil {
+				log.Printf("Skipping token %d: %s", tokenID, err)
+				continue
 			}
+			manifest = append(manifest, spec)
+			specs <- spec
 		}
+
+		utils.SetRandomizer(true)
 	}()
 
-	var wg sync.WaitGroup
-	workers := make(chan struct{}, maxWorkers)
+	results := openResults()
+	runPipeline(specs, results)
 
-	if nrNFTs > max_NFTS {
-		nrNFTs = max_NFTS
-	}
+	// The manifest has the plan format, so the run can be rendered again. It
+	// is kept locally, as single token runs replay from it.
+	writeToSimpleFile(manifestFile, manifest)
 
-	for tokenID := 0; tokenID < nrNFTs; tokenID++ {
-		workers <- struct{}{}
-		wg.Add(1)
+	// Only the committed tokens are counted, once every token is final.
+	writeResult(results, "rarity.json", traitDistribution(manifest))
+
+	closeResults(results)
+}
 
-		go processToken(&wg, workers, nil, tr.Copy(), tokenID)
+// oneOfOneSpec routes a hand-made token around trait selection. When the
+// one_of_ones folder of the configuration holds its artwork, that file is
+// published as the token image; otherwise the token keeps the image it already
+// has. The artwork must be a PNG, as it is published as images/{tokenID}.png.
+func oneOfOneSpec(tokenID int) TokenSpec {
+	spec := TokenSpec{
+		TokenID:  tokenID,
+		Metadata: responses[tokenID],
+		OneOfOne: true,
 	}
 
-	utils.SetRandomizer(true)
+	folder := config.Get().OneOfOnes
+	artwork := filepath.Join(folder, fmt.Sprintf("%d.png", tokenID))
+	if _, err := os.Stat(artwork); err == nil {
+		spec.Artwork = artwork
+		return spec
+	}
 
-	wg.Wait()
+	// Artwork in another format is not converted, so it would be ignored silently.
+	if others, _ := filepath.Glob(filepath.Join(folder, fmt.Sprintf("%d.*", tokenID))); len(others) > 0 {
+		log.Printf("Ignoring artwork %s of token %d: only PNG artwork is published", others[0], tokenID)
+	}
+	log.Printf("No artwork for token %d, keeping its image %s", tokenID, spec.Metadata.Image)
 
-	writeToSimpleFile("./assets/results/rarity.json", rarities)
+	return spec
 }
 
+// TraitData is a single layer picked for a token.
 type TraitData struct {
-	Folder   string
-	Name     string
-	FileName string
+	Slot     models.Slot `json:"slot"`
+	Folder   string      `json:"folder"`
+	Name     string      `json:"name"`
+	FileName string      `json:"file_name"`
 }
 
-func processToken(wg *sync.WaitGroup, done <-chan struct{}, r *utils.Randomizer, traits *models.Traits, tokenID int) {
-	defer wg.Done()
-	defer func() {
-		log.Printf("Done processing token %d", tokenID)
-		<-done
-	}()
+// maxSelectionRuns bounds the runs of the selection of one token, backtracking included.
+const maxSelectionRuns = 1000
+
+// errNoSpecie reports a token whose metadata has no specie, so no trait can be picked.
+var errNoSpecie = errors.New("specie not found")
+
+// processToken is the selection stage: it picks the traits of a token, takes
+// the uniqueness lock and returns the layers to composite. It does no image
+// work so it can run ahead of the compositing workers.
+//
+// Each run of the selection is an isolated attempt. When a run reaches a dead
+// end or breaks a compatibility rule, the decisions that caused it are revised
+// and the others replayed; when it finds a duplicate, its latest decisions are
+// revised first. Revised decisions are drawn again among the options left,
+// with their weights. If no run succeeds, the token keeps a duplicate, or else
+// a combination breaking the rules. When no run even completed, an error is
+// returned and the token has no spec.
+func processToken(r *utils.Randomizer, traits *models.Traits, tokenID int) (TokenSpec, error) {
+	if responses[tokenID].GetRarity().IsOneOfOne() {
+		return oneOfOneSpec(tokenID), nil
+	}
 
-	var paths []string
-	var index int
-bigfor:
-	for {
-		if utils.IsRandomizerDone() && index != 0 || index > 10 {
-			if !utils.IsRandomizerDone() {
-				log.Printf("Failed to generate token %d", tokenID)
-			}
+	if r == nil {
+		seed := uuid.NewString()
+		r = utils.NewRandomizerWith(config.Get().RandomSource, seed, tokenID)
+	}
+
+	trace := r.StartTrace()
+	defer r.StopTrace()
+
+	var attempt, fallback *tokenAttempt
+	var err error
+	duplicate := false
+	for runs := 1; ; runs++ {
+		trace.Rewind()
+
+		attempt, err = newAttempt(r, traits, tokenID)
+		if err == errNoSpecie {
+			log.Printf("Specie not found for token %d", tokenID)
 			break
 		}
 
-		index++
+		var violations []error
+		if err == nil {
+			violations = attempt.violations
+			if len(violations) == 0 && attempt.commit() {
+				break
+			}
+			if fallback == nil || len(fallback.violations) > 0 {
+				fallback = attempt
+			}
+		}
 
-		c := traits.Copy()
+		if utils.IsRandomizerDone() || runs >= maxSelectionRuns || !backtrack(trace, err, violations) {
+			if fallback == nil {
+				return TokenSpec{}, fmt.Errorf("no valid combination for token %d: %v", tokenID, err)
+			}
 
-		metadata := responses[tokenID]
+			log.Printf("Failed to generate token %d", tokenID)
+			for _, violation := range fallback.violations {
+				log.Printf("Token %d: %s", tokenID, violation)
+			}
 
-		if r == nil {
-			seed := uuid.NewString()
-			r = utils.NewRandomizer(seed)
+			attempt, err = fallback, nil
+			duplicate = len(attempt.violations) == 0 || !attempt.commit()
+			break
 		}
-		metadata.Seed = r.Seed
+	}
 
-		rarity := metadata.GetRarity()
-		switch rarity {
-		case models.ONE_OF_ONE, models.UNKNOWN_COLOR1, models.UNKNOWN_COLOR2, models.UNKNOWN_COLOR3:
-			break bigfor
-		}
+	spec := newTokenSpec(tokenID, attempt.final, attempt.layers, attempt.metadata)
+	spec.Duplicate = duplicate
+	for _, violation := range attempt.violations {
+		spec.Violations = append(spec.Violations, violation.Error())
+	}
+	spec.Source = r.Kind
 
-		c.Final.Rarity = rarity
-		c.Final.Specie = metadata.GetSpecie()
-		c.Final.Metadata = metadata
-		if c.Final.Specie == models.SpecieNone {
-			log.Printf("Specie not found for token %d", tokenID)
-			break
+	return spec, nil
+}
+
+// backtrack revises the decisions of a failed run: the causes of a dead end,
+// the picks breaking a rule, or the latest decisions otherwise. Returns false
+// when nothing can be revised.
+func backtrack(trace *utils.Trace, err error, violations []error) bool {
+	var causes []string
+
+	var deadEnd *processor.DeadEndError
+	if errors.As(err, &deadEnd) {
+		if len(deadEnd.Causes) == 0 {
+			return false
 		}
-		c.Final.Category = r.RandomCategory()
-		c.Final.Gender = r.RandomGender()
+		causes = deadEnd.Causes
+	}
 
-		if c.Final.Specie == models.SpecieMonkey {
-			c.Final.HasHair = false
-		} else if c.Final.Gender == models.GenderFemale {
-			c.Final.HasHair = r.HasHair(100)
-		} else {
-			c.Final.HasHair = r.HasHair(50)
+	for _, err := range violations {
+		var violation *processor.Violation
+		if errors.As(err, &violation) {
+			for _, slot := range violation.Slots {
+				causes = append(causes, slot.String())
+			}
 		}
+	}
 
-		processor.Process(r, c)
+	if len(causes) > 0 && trace.BacktrackTo(causes...) {
+		return true
+	}
 
-		var key string
-		add := func(common *models.Common, folderName, traitName string) {
-			if common == nil {
-				return
-			}
+	return trace.Backtrack()
+}
 
-			if traitName != "" {
-				c.Final.Metadata.Attributes = append(c.Final.Metadata.Attributes, models.Attribute{
-					TraitType: traitName,
-					Value:     common.OpenSeaTraitValue,
-				})
-			}
+// tokenAttempt is one try at picking the traits of a token. It owns its copy
+// of the traits and of the token metadata, so a discarded attempt leaves
+// nothing behind.
+type tokenAttempt struct {
+	final      models.FinalTraits
+	layers     []TraitData
+	metadata   *models.APIResponse
+	key        string  // Combination of the attempt, checked for uniqueness.
+	violations []error // Compatibility rules broken by the attempt.
+}
 
-			key += common.OpenSeaTraitValue
+// newAttempt picks the traits of a token. A panic of the selection rules is
+// returned as an error, so the attempt can be revised like any dead end.
+func newAttempt(r *utils.Randomizer, traits *models.Traits, tokenID int) (attempt *tokenAttempt, err error) {
+	c := traits.Copy()
 
-			paths = append(paths, baseFolder+folderName+"/"+common.FileName+".png")
+	metadata := responses[tokenID].Copy()
+	metadata.Seed = r.Seed
 
-			ram <- TraitData{
-				Folder:   folderName,
-				Name:     common.OpenSeaTraitValue,
-				FileName: common.FileName,
-			}
+	c.Final.Rarity = metadata.GetRarity()
+	c.Final.Specie = metadata.GetSpecie()
+	c.Final.Metadata = metadata
+
+	attempt = &tokenAttempt{
+		final:    c.Final,
+		metadata: metadata,
+	}
+
+	if c.Final.Specie == models.SpecieNone {
+		return attempt, errNoSpecie
+	}
+
+	probabilities := config.Get().Probabilities.For(c.Final.Specie, c.Final.Rarity)
+	r.Label(models.DerivedCategory.String())
+	c.Final.Category = r.RandomCategory(probabilities.Category)
+	r.Label(models.DerivedGender.String())
+	c.Final.Gender = r.RandomGender(probabilities.Gender)
+
+	if c.Final.Specie == models.SpecieMonkey {
+		c.Final.HasHair = false
+	} else {
+		r.Label(models.DerivedHasHair.String())
+		c.Final.HasHair = r.HasHair(probabilities.Hair[c.Final.Gender])
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("selection failed: %v", p)
 		}
+	}()
+
+	if err := processor.Process(r, c); err != nil {
+		return nil, err
+	}
+	attempt.final = c.Final
+	attempt.violations = processor.Validate(traits, c.Final)
 
-		add(c.Final.BG, "BACKGROUND", "Background")
-		add(c.Final.BGAccent, "BACKGROUND ACCENT", "Background")
-		add(c.Final.Droplets.DataBack, "DROPLETS (BACK)", "Rarity")
-		add(c.Final.Aura.Back, "AURA (BACK)", "Background")
-		add(c.Final.Tails, "TAILS", "")
-		add(c.Final.Wings, "WINGS", "Wings")
-		add(c.Final.Weapons.Back, "WEAPONS (BACK)", "Weapon")
-		add(c.Final.Droplets.DataBackTransparent, "DROPLET (BACK TRANSPARENT)", "Rarity")
-		add(c.Final.StackableHats.DataBack, "HATS (STACKABLE)", "Hat")
-		add(c.Final.Hairs.HairBack, "HAIR (BACK)", "Hair")
-		add(c.Final.Bodies, "BODIES", "Body")
-		add(c.Final.Facegears, "FACE GEAR", "Face")
-		add(c.Final.Clothes, "CLOTHES", "Clothes")
-		add(c.Final.Hands, "HANDS", "")
-		add(c.Final.Weapons.Front, "WEAPONS (FRONT)", "Weapons")
-		add(c.Final.Eyes, "EYES", "Eyes")
-		add(c.Final.Mouths, "MOUTH", "Mouth")
-		add(c.Final.Nose, "NOSE", "")
-		add(c.Final.Hairs.Hair, "HAIR", "Hair")
-		add(c.Final.Hats.Data, "HATS", "Hat")
-		add(c.Final.Hats.DataEarless, "HATS", "Hat")
-		add(c.Final.StackableHats.DataFront, "HATS (STACKABLE)", "Hat")
-		add(c.Final.ElvenEars, "ELVEN EARS", "")
-		add(c.Final.Earrings, "EARRINGS", "")
-		add(c.Final.Glasses, "GLASSES", "Glasses")
-		add(c.Final.Droplets.DataFront, "DROPLETS", "Rarity")
-		add(c.Final.Aura.Front, "AURA (FRONT)", "Background")
-
-		mu.Lock()
-		if _, ok := m[key]; ok {
-			// log.Println("Duplicate found: ", key)
-			mu.Unlock()
+	for _, slot := range models.SlotList() {
+		common := c.Final.Get(slot)
+		if common == nil {
 			continue
 		}
-		m[key] = struct{}{}
-		mu.Unlock()
 
-		break
+		if attribute, ok := slotAttribute(slot, common); ok {
+			metadata.Attributes = append(metadata.Attributes, attribute)
+		}
+
+		attempt.key += common.OpenSeaTraitValue
+		attempt.layers = append(attempt.layers, TraitData{
+			Slot:     slot,
+			Folder:   slot.Folder(),
+			Name:     common.OpenSeaTraitValue,
+			FileName: common.FileName,
+		})
 	}
+	metadata.Attributes = append(metadata.Attributes, derivedAttributes(c.Final)...)
 
-	g := generator.NewImageCreator(tokenID, paths)
+	return attempt, nil
+}
 
-	g.Process()
+// commit takes the uniqueness lock on the combination of the attempt.
+// Returns false if another token already holds it.
+func (a *tokenAttempt) commit() bool {
+	mu.Lock()
+	defer mu.Unlock()
 
-	g.WriteTo(fmt.Sprintf("./assets/results/images/%d.png", tokenID))
+	if _, ok := m[a.key]; ok {
+		return false
+	}
+	m[a.key] = struct{}{}
 
-	metadata := responses[tokenID]
-	metadata.MakeAttributesUnique()
-	metadata.AnimationURL = ""
-	metadata.Image = fmt.Sprintf("https://ipfs.io/ipfs/REPLACE_ME/%d.jpg", tokenID)
-	writeToSimpleFile(fmt.Sprintf("./assets/results/metadata/%d.json", tokenID), metadata)
+	return true
 }
 
 func writeToSimpleFile(name string, data interface{}) {
@@ -264,7 +526,7 @@ func writeToSimpleFile(name string, data interface{}) {
 	if err != nil {
 		fmt.Println(err)
 	}
-	err = ioutil.WriteFile(name, body, 0777)
+	err = ioutil.WriteFile(name, body, 0644)
 	if err != nil {
 		fmt.Println(err)
 		return
@@ -3,6 +3,7 @@ package models
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 )
 
@@ -20,7 +21,28 @@ type APIResponse struct {
 	Description  string      `json:"description"`             // Description of the token.
 	Image        string      `json:"image"`                   // URL to the image of the token.
 	AnimationURL string      `json:"animation_url,omitempty"` // Optional animation URL.
+	ExternalURL  string      `json:"external_url,omitempty"`  // Optional URL of the token page.
 	Attributes   []Attribute `json:"attributes"`              // List of attributes for the token.
+	Properties   *Properties `json:"properties,omitempty"`    // Optional values decided during generation.
+}
+
+// Properties records the values decided during generation that drive the trait
+// selection but are not layers of the image.
+type Properties struct {
+	Category Category `json:"category"` // Category picked for the token.
+	Gender   Gender   `json:"gender"`   // Gender picked for the token.
+	HasHair  bool     `json:"has_hair"` // Whether hair could be picked for the token.
+}
+
+// Copy returns a copy of the response that can be changed without affecting the original.
+func (a *APIResponse) Copy() *APIResponse {
+	c := *a
+	c.Attributes = append([]Attribute{}, a.Attributes...)
+	if a.Properties != nil {
+		properties := *a.Properties
+		c.Properties = &properties
+	}
+	return &c
 }
 
 // GetSpecie retrieves the "Species" attribute from the APIResponse.
@@ -63,24 +85,54 @@ func (a *APIResponse) GetRarity() Rarity {
 	panic(fmt.Errorf("no rarity found for token ID: %v", a.TokenID))
 }
 
+// AttributeOptions controls the order and the merging of attributes applied by MakeAttributesUnique.
+type AttributeOptions struct {
+	Order     []string               `json:"order"`     // Trait types listed first, in this order; the others follow in their original order.
+	Merge     map[string]MergePolicy `json:"merge"`     // Merge policy by trait type; trait types not listed keep every value.
+	Separator string                 `json:"separator"` // Separator used by the join policy, ", " by default.
+}
+
 // MakeAttributesUnique ensures that the attributes in the APIResponse are unique by both trait type and value.
-func (a *APIResponse) MakeAttributesUnique() {
-	data := make(map[string]map[string]struct{}) // Nested map to track unique attributes.
+// The attributes keep their original order, except for the trait types prioritized by opts.Order,
+// and several values of one trait type are merged according to opts.Merge.
+func (a *APIResponse) MakeAttributesUnique(opts AttributeOptions) {
+	var traitTypes []string                  // Trait types in order of first appearance.
+	values := make(map[string][]string)      // Distinct values by trait type, in order of appearance.
+	seen := make(map[string]map[string]bool) // Nested map to track unique attributes.
 
-	// Populate the nested map with existing attributes.
 	for _, x := range a.Attributes {
-		if _, ok := data[x.TraitType]; !ok {
-			data[x.TraitType] = make(map[string]struct{})
+		if _, ok := seen[x.TraitType]; !ok {
+			seen[x.TraitType] = make(map[string]bool)
+			traitTypes = append(traitTypes, x.TraitType)
 		}
-		data[x.TraitType][x.Value] = struct{}{}
+		if seen[x.TraitType][x.Value] {
+			continue
+		}
+		seen[x.TraitType][x.Value] = true
+		values[x.TraitType] = append(values[x.TraitType], x.Value)
 	}
 
-	// Clear the original attributes slice.
-	a.Attributes = []Attribute{}
+	// Move the prioritized trait types to the front, keeping the others in place.
+	priority := make(map[string]int, len(opts.Order))
+	for i, traitType := range opts.Order {
+		if _, ok := priority[traitType]; !ok {
+			priority[traitType] = i
+		}
+	}
+	rank := func(traitType string) int {
+		if i, ok := priority[traitType]; ok {
+			return i
+		}
+		return len(opts.Order)
+	}
+	sort.SliceStable(traitTypes, func(i, j int) bool {
+		return rank(traitTypes[i]) < rank(traitTypes[j])
+	})
 
 	// Rebuild the attributes slice with unique values.
-	for traitType, values := range data {
-		for value := range values {
+	a.Attributes = []Attribute{}
+	for _, traitType := range traitTypes {
+		for _, value := range opts.Merge[traitType].Apply(values[traitType], opts.Separator) {
 			a.Attributes = append(a.Attributes, Attribute{
 				TraitType: traitType,
 				Value:     value,
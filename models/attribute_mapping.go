@@ -0,0 +1,38 @@
+package models
+
+// AttributeMapping decides whether and how a slot, or a derived value, is reported
+// as a metadata attribute. The zero value keeps the default behaviour.
+type AttributeMapping struct {
+	Emit      *bool             `json:"emit,omitempty"`       // Whether an attribute is emitted; unset keeps the default.
+	TraitType string            `json:"trait_type,omitempty"` // Trait type of the attribute; empty keeps the default.
+	Values    map[string]string `json:"values,omitempty"`     // Value rewrites, by original value.
+	Merge     MergePolicy       `json:"merge,omitempty"`      // Merge policy of the trait type, unless set in the attribute options.
+}
+
+// Attribute returns the attribute reporting value under the mapping. traitType is the default
+// trait type and emitByDefault tells whether the attribute is emitted when the mapping does not
+// say so; setting a trait type in the mapping also enables it. Returns false when no attribute
+// is emitted.
+func (m AttributeMapping) Attribute(traitType string, emitByDefault bool, value string) (Attribute, bool) {
+	emit := emitByDefault || m.TraitType != ""
+	if m.Emit != nil {
+		emit = *m.Emit
+	}
+
+	if m.TraitType != "" {
+		traitType = m.TraitType
+	}
+
+	if !emit || traitType == "" || value == "" {
+		return Attribute{}, false
+	}
+
+	if rewritten, ok := m.Values[value]; ok {
+		value = rewritten
+	}
+
+	return Attribute{
+		TraitType: traitType,
+		Value:     value,
+	}, true
+}
@@ -8,6 +8,9 @@ type Commons struct {
 
 // Copy creates a deep copy of the Commons object.
 func (c *Commons) Copy() *Commons {
+	if c == nil {
+		return nil
+	}
 	return &Commons{
 		NA:   c.NA,                           // Copy reference to the NA field
 		Data: append([]*Common{}, c.Data...), // Create a new slice with copied references
@@ -27,6 +30,8 @@ type Common struct {
 	Notes                  string       // Notes or additional information
 	MustInclude            []string     // List of strings that must be included
 	RarityLocked           RarityLocked // Locked rarity information
+	Rarities               []Rarity     // Rarities the item is restricted to; any rarity if empty
+	ExcludedRarities       []Rarity     // Rarities the item is excluded from
 	AbleToHaveStackableHat bool         // Indicates if stackable hats are allowed
 	OnlyHaloAndHorns       bool         // Indicates if only halo and horns are allowed
 }
@@ -48,6 +53,8 @@ func (c *Common) Copy() *Common {
 		Notes:                  c.Notes,
 		MustInclude:            append([]string{}, c.MustInclude...), // Create a new slice for MustInclude
 		RarityLocked:           c.RarityLocked,
+		Rarities:               append([]Rarity{}, c.Rarities...),         // Create a new slice for Rarities
+		ExcludedRarities:       append([]Rarity{}, c.ExcludedRarities...), // Create a new slice for ExcludedRarities
 		AbleToHaveStackableHat: c.AbleToHaveStackableHat,
 		OnlyHaloAndHorns:       c.OnlyHaloAndHorns,
 	}
@@ -97,6 +104,80 @@ type Weapons struct {
 	NA    *Common   // Not Applicable weapon
 }
 
+// Copy creates a copy of an Aura with new slices.
+func (a *Aura) Copy() *Aura {
+	if a == nil {
+		return nil
+	}
+	return &Aura{
+		Normal: append([]*Common{}, a.Normal...),
+		Front:  append([]*Common{}, a.Front...),
+		NA:     a.NA,
+	}
+}
+
+// Copy creates a copy of a Hairs with new slices.
+func (h *Hairs) Copy() *Hairs {
+	if h == nil {
+		return nil
+	}
+	return &Hairs{
+		Hair:     append([]*Common{}, h.Hair...),
+		HairBack: append([]*Common{}, h.HairBack...),
+		NA:       h.NA,
+	}
+}
+
+// Copy creates a copy of a Hats with new slices.
+func (h *Hats) Copy() *Hats {
+	if h == nil {
+		return nil
+	}
+	return &Hats{
+		Data:        append([]*Common{}, h.Data...),
+		DataEarless: append([]*Common{}, h.DataEarless...),
+		NA:          h.NA,
+		NAEarless:   h.NAEarless,
+	}
+}
+
+// Copy creates a copy of a StackableHats with new slices.
+func (s *StackableHats) Copy() *StackableHats {
+	if s == nil {
+		return nil
+	}
+	return &StackableHats{
+		Data:     append([]*Common{}, s.Data...),
+		DataBack: append([]*Common{}, s.DataBack...),
+		NA:       s.NA,
+	}
+}
+
+// Copy creates a copy of a Droplets with new slices.
+func (d *Droplets) Copy() *Droplets {
+	if d == nil {
+		return nil
+	}
+	return &Droplets{
+		Data:                append([]*Common{}, d.Data...),
+		DataBack:            append([]*Common{}, d.DataBack...),
+		DataBackTransparent: append([]*Common{}, d.DataBackTransparent...),
+		NA:                  d.NA,
+	}
+}
+
+// Copy creates a copy of a Weapons with new slices.
+func (w *Weapons) Copy() *Weapons {
+	if w == nil {
+		return nil
+	}
+	return &Weapons{
+		Front: append([]*Common{}, w.Front...),
+		Back:  append([]*Common{}, w.Back...),
+		NA:    w.NA,
+	}
+}
+
 // Single item variants of the above types:
 
 // AuraSingle represents a single aura with front and back components.
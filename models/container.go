@@ -89,38 +89,64 @@ func ByGender[T any](gender Gender, male, female []T) []T {
 	}
 }
 
-// Copy creates a deep copy of a Traits object.
+// Copy creates a deep copy of a Traits object. The items themselves are
+// shared, but every list is copied, so filtering the copy leaves the original intact.
 func (f Traits) Copy() *Traits {
 	return &Traits{
-		// Perform deep copies of all trait groups
-		Bodies:    f.Bodies.Copy(),
-		Tails:     f.Tails.Copy(),
-		ElvenEars: f.ElvenEars.Copy(),
-		Droplets: &Droplets{
-			NA:                  f.Droplets.NA,
-			Data:                append([]*Common{}, f.Droplets.Data...),
-			DataBack:            append([]*Common{}, f.Droplets.DataBack...),
-			DataBackTransparent: append([]*Common{}, f.Droplets.DataBackTransparent...),
-		},
-		// Similar deep copies for other traits...
+		Bodies:        f.Bodies.Copy(),
+		Tails:         f.Tails.Copy(),
+		ElvenEars:     f.ElvenEars.Copy(),
+		Droplets:      f.Droplets.Copy(),
+		Hands:         f.Hands.Copy(),
+		Hairs:         f.Hairs.Copy(),
+		Hats:          f.Hats.Copy(),
+		StackableHats: f.StackableHats.Copy(),
+		Mouths:        f.Mouths.Copy(),
+		Nose:          f.Nose.Copy(),
+		Eyes:          f.Eyes.Copy(),
+		Glasses:       f.Glasses.Copy(),
+		Earrings:      f.Earrings.Copy(),
+		Clothes:       f.Clothes.Copy(),
+		Wings:         f.Wings.Copy(),
+		Weapons:       f.Weapons.Copy(),
+		Facegears:     f.Facegears.Copy(),
+		BG:            f.BG.Copy(),
+		BGAccent:      f.BGAccent.Copy(),
+		Aura:          f.Aura.Copy(),
+
+		DefaultMaleClothes:        f.DefaultMaleClothes.Copy(),
+		DefaultFemaleClothes:      f.DefaultFemaleClothes.Copy(),
+		DefaultMaleMouths:         f.DefaultMaleMouths.Copy(),
+		DefaultFemaletMouths:      f.DefaultFemaletMouths.Copy(),
+		DefaultMaleEyes:           f.DefaultMaleEyes.Copy(),
+		DefaultFemaleEyes:         f.DefaultFemaleEyes.Copy(),
+		DefaultMaleHair:           f.DefaultMaleHair.Copy(),
+		DefaultFemaleHair:         f.DefaultFemaleHair.Copy(),
+		DefaultMaleHat:            f.DefaultMaleHat.Copy(),
+		DefaultFemaleHat:          f.DefaultFemaleHat.Copy(),
+		DefaultMaleStackableHat:   f.DefaultMaleStackableHat.Copy(),
+		DefaultFemaleStackableHat: f.DefaultFemaleStackableHat.Copy(),
+
 		Final: f.Final.Copy(),
 	}
 }
 
-// Copy creates a deep copy of a FinalTraits object.
+// Copy creates a deep copy of a FinalTraits object. The metadata is shared.
 func (f FinalTraits) Copy() FinalTraits {
-	return FinalTraits{
-		// Deep copy for all fields in FinalTraits
-		Bodies:    f.Bodies.Copy(),
-		Tails:     f.Tails.Copy(),
-		ElvenEars: f.ElvenEars.Copy(),
-		Droplets: DropletsSingle{
-			DataFront:           f.Droplets.DataFront.Copy(),
-			DataBack:            f.Droplets.DataBack.Copy(),
-			DataBackTransparent: f.Droplets.DataBackTransparent.Copy(),
-		},
-		// Other fields copied similarly...
+	result := FinalTraits{
+		Metadata: f.Metadata,
+		Category: f.Category,
+		Gender:   f.Gender,
+		Rarity:   f.Rarity,
+		Specie:   f.Specie,
+		HasHair:  f.HasHair,
+	}
+
+	for _, slot := range SlotList() {
+		result.Set(slot, f.Get(slot).Copy())
 	}
+
+	return result
 }
 
 // Filter types for default traits.
@@ -130,6 +156,7 @@ const (
 	FilterGender Filter = iota
 	FilterCategory
 	FilterSpecie
+	FilterRarity
 )
 
 // DefaultFilter filters Commons based on the provided filters and FinalTraits criteria.
@@ -168,6 +195,11 @@ func (f FinalTraits) DefaultFilter(data []*Common, filters ...Filter) []*Common
 		return true
 	})
 
+	// Apply rarity filtering if specified
+	if len(filters) == 0 || lo.Contains(filters, FilterRarity) {
+		result = f.FilterByRarity(result)
+	}
+
 	// Apply species filtering if specified
 	if len(filters) == 0 || lo.Contains(filters, FilterSpecie) {
 		return f.FilterBySpecies(result)
@@ -176,20 +208,32 @@ func (f FinalTraits) DefaultFilter(data []*Common, filters ...Filter) []*Common
 	return result
 }
 
+// FilterByRarity filters Commons based on the rarities they are restricted to or excluded from.
+func (f FinalTraits) FilterByRarity(data []*Common) []*Common {
+	return lo.Filter(data, func(common *Common, i int) bool {
+		return common.AllowsRarity(f.Rarity)
+	})
+}
+
 // FilterBySpecies filters Commons based on species constraints in FinalTraits.
 func (f FinalTraits) FilterBySpecies(data []*Common) []*Common {
-	specie := f.Specie
-
 	return lo.Filter(data, func(common *Common, i int) bool {
-		return lo.Contains(common.SpeciesLocked, specie) ||
-			lo.Contains(common.SpeciesLocked, SpecieNone) &&
-				specie != SpecieSoul ||
-			common.RarityLocked.IsY() &&
-				specie != SpecieOrigin &&
-				specie != SpecieSoul
+		return common.AllowsSpecie(f.Specie)
 	})
 }
 
+// AllowsSpecie checks if the item can be used by a token of the given species:
+// the item is locked to that species, open to every species but Soul, or
+// flagged by RarityLocked, which opens it to every species but Origin and Soul.
+func (c *Common) AllowsSpecie(specie Specie) bool {
+	return lo.Contains(c.SpeciesLocked, specie) ||
+		lo.Contains(c.SpeciesLocked, SpecieNone) &&
+			specie != SpecieSoul ||
+		c.RarityLocked.IsY() &&
+			specie != SpecieOrigin &&
+			specie != SpecieSoul
+}
+
 // PopulateByGender organizes Commons by gender.
 func PopulateByGender(data []*Common) map[Gender][]*Common {
 	result := make(map[Gender][]*Common)
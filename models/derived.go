@@ -0,0 +1,70 @@
+package models
+
+import "strings"
+
+// Derived identifies a value decided during selection that is not a layer of the image,
+// but can be reported as a metadata attribute.
+type Derived string
+
+// Constants representing the derived values; each one is also its default trait type.
+const (
+	DerivedSpecies  Derived = "Species"  // Species of the token
+	DerivedGender   Derived = "Gender"   // Gender picked for the token
+	DerivedCategory Derived = "Category" // Category picked for the token
+	DerivedHasHair  Derived = "Has Hair" // Whether hair could be picked for the token
+)
+
+// DerivedList returns every derived value, in the order their attributes are emitted.
+func DerivedList() []Derived {
+	return []Derived{DerivedSpecies, DerivedGender, DerivedCategory, DerivedHasHair}
+}
+
+// String returns the string representation of the Derived value.
+func (d Derived) String() string {
+	return string(d)
+}
+
+// Derived returns the display value of a derived value of the traits,
+// or an empty string if it was not decided.
+func (f FinalTraits) Derived(d Derived) string {
+	switch d {
+	case DerivedSpecies:
+		if f.Specie == SpecieNone || f.Specie == SpecieNA {
+			return ""
+		}
+		return titleCase(f.Specie.String())
+	case DerivedGender:
+		switch f.Gender {
+		case GenderMale:
+			return "Male"
+		case GenderFemale:
+			return "Female"
+		default:
+			return ""
+		}
+	case DerivedCategory:
+		if f.Category.IsEmpty() || f.Category == CategoryNA {
+			return ""
+		}
+		return titleCase(f.Category.String())
+	case DerivedHasHair:
+		// Hair is decided right after the gender; without a gender it was not decided.
+		if f.Gender == "" {
+			return ""
+		}
+		if f.HasHair {
+			return "Yes"
+		}
+		return "No"
+	default:
+		return ""
+	}
+}
+
+// titleCase converts an upper-case identifier such as "FELINE" to "Feline".
+func titleCase(value string) string {
+	if value == "" {
+		return value
+	}
+	return value[:1] + strings.ToLower(value[1:])
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -72,3 +74,29 @@ func (d Distribution) GetPercentage() float64 {
 
 	return parsefloat
 }
+
+// GetRat parses and returns the exact percentage value of the distribution, so that
+// weights such as "0.0004%" are not rounded away.
+// If the distribution is invalid or empty, it returns 0. If it's revealed, it returns 100.
+func (d Distribution) GetRat() *big.Rat {
+	if !d.IsValid() {
+		return new(big.Rat) // Return 0 for invalid distributions
+	}
+
+	if d.IsRevealed() {
+		return big.NewRat(100, 1) // Return 100% for revealed distributions
+	}
+
+	value := strings.TrimSpace(strings.Replace(d.String(), "%", "", 1))
+
+	if value == "" {
+		return new(big.Rat) // Return 0 if no value is found
+	}
+
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok {
+		panic(fmt.Errorf("invalid distribution: %s", d)) // Consistent with GetPercentage
+	}
+
+	return rat
+}
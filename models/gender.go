@@ -11,6 +11,11 @@ const (
 	GenderUnisex Gender = "U"  // Unisex or applicable to both genders
 )
 
+// GenderList returns a slice of the genders a token can have.
+func GenderList() []Gender {
+	return []Gender{GenderMale, GenderFemale}
+}
+
 // IsValid checks if the gender is one of the predefined valid values.
 func (g Gender) IsValid() bool {
 	switch g {
@@ -0,0 +1,55 @@
+package models
+
+import "strings"
+
+// MergePolicy decides how several values of the same trait type are reported,
+// such as a hat and a stackable hat both reported under "Hat".
+type MergePolicy string
+
+// Constants representing valid merge policies.
+const (
+	MergeKeep  MergePolicy = ""      // Keep one attribute per distinct value (default)
+	MergeFirst MergePolicy = "first" // Keep the first value only
+	MergeLast  MergePolicy = "last"  // Keep the last value only
+	MergeJoin  MergePolicy = "join"  // Join all values into a single attribute
+)
+
+// defaultSeparator joins merged values when no separator is configured.
+const defaultSeparator = ", "
+
+// IsValid checks if the merge policy is one of the predefined valid values.
+func (m MergePolicy) IsValid() bool {
+	switch m {
+	case MergeKeep, MergeFirst, MergeLast, MergeJoin:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsInvalid checks if the merge policy is invalid by negating IsValid.
+func (m MergePolicy) IsInvalid() bool {
+	return !m.IsValid()
+}
+
+// Apply merges the distinct values of a trait type, given in their original order.
+// The separator is only used by MergeJoin; an empty separator means ", ".
+func (m MergePolicy) Apply(values []string, separator string) []string {
+	if len(values) < 2 {
+		return values
+	}
+
+	switch m {
+	case MergeFirst:
+		return values[:1]
+	case MergeLast:
+		return values[len(values)-1:]
+	case MergeJoin:
+		if separator == "" {
+			separator = defaultSeparator
+		}
+		return []string{strings.Join(values, separator)}
+	default:
+		return values
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+// Probabilities holds the weights, in percent, of the values decided before the
+// trait selection. A table left empty keeps the one it overrides.
+type Probabilities struct {
+	Category map[Category]float64 `json:"category,omitempty"` // Weight of each category.
+	Gender   map[Gender]float64   `json:"gender,omitempty"`   // Weight of each gender.
+	Hair     map[Gender]float64   `json:"hair,omitempty"`     // Chance of having hair, by gender.
+}
+
+// DefaultProbabilities returns the tables used when nothing is configured.
+func DefaultProbabilities() Probabilities {
+	p := Probabilities{
+		Category: make(map[Category]float64),
+		Gender: map[Gender]float64{
+			GenderMale:   float64(GenderMale.ToPercentage()),
+			GenderFemale: float64(GenderFemale.ToPercentage()),
+		},
+		Hair: map[Gender]float64{
+			GenderMale:   50,
+			GenderFemale: 100,
+		},
+	}
+
+	for _, category := range CategoryList() {
+		p.Category[category] = float64(category.ToPercentage())
+	}
+
+	return p
+}
+
+// Override returns the tables of p replaced by the non-empty tables of o.
+func (p Probabilities) Override(o Probabilities) Probabilities {
+	if len(o.Category) > 0 {
+		p.Category = o.Category
+	}
+	if len(o.Gender) > 0 {
+		p.Gender = o.Gender
+	}
+	if len(o.Hair) > 0 {
+		p.Hair = o.Hair
+	}
+	return p
+}
+
+// ProbabilityTables holds the configured probabilities, with overrides by species and by rarity.
+type ProbabilityTables struct {
+	Default  Probabilities            `json:"default"`   // Overrides of DefaultProbabilities for every token.
+	BySpecie map[Specie]Probabilities `json:"by_specie"` // Overrides for the tokens of a species.
+	ByRarity map[Rarity]Probabilities `json:"by_rarity"` // Overrides for the tokens of a rarity, applied last.
+}
+
+// For returns the probabilities of a token of the given species and rarity.
+func (t ProbabilityTables) For(specie Specie, rarity Rarity) Probabilities {
+	return DefaultProbabilities().
+		Override(t.Default).
+		Override(t.BySpecie[specie]).
+		Override(t.ByRarity[rarity])
+}
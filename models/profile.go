@@ -0,0 +1,232 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Profile represents a metadata standard the generated metadata can be exported to.
+type Profile string
+
+// Constants representing the supported profiles.
+const (
+	ProfileDefault  Profile = "default"  // The APIResponse shape, as generated
+	ProfileOpenSea  Profile = "opensea"  // OpenSea metadata standard
+	ProfileERC721   Profile = "erc721"   // ERC-721 metadata JSON schema
+	ProfileMetaplex Profile = "metaplex" // Metaplex token metadata standard (Solana)
+)
+
+// IsValid checks if the profile is one of the supported profiles.
+func (p Profile) IsValid() bool {
+	switch p {
+	case ProfileDefault, ProfileOpenSea, ProfileERC721, ProfileMetaplex:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsInvalid checks if the profile is invalid by negating IsValid.
+func (p Profile) IsInvalid() bool {
+	return !p.IsValid()
+}
+
+// String returns the string representation of the Profile.
+func (p Profile) String() string {
+	return string(p)
+}
+
+// ProfileOptions holds the collection-wide values the profiles add to the generated metadata.
+type ProfileOptions struct {
+	ExternalURL          string                  `json:"external_url"`            // Template of the token page URL, "{id}" is replaced by the token ID.
+	BackgroundColor      string                  `json:"background_color"`        // Six-character hex background color, without "#".
+	NumericTraits        map[string]NumericTrait `json:"numeric_traits"`          // Trait types exported as numbers, by trait type.
+	Symbol               string                  `json:"symbol"`                  // Metaplex collection symbol.
+	SellerFeeBasisPoints int                     `json:"seller_fee_basis_points"` // Metaplex royalties, 500 meaning 5%.
+	Creators             []Creator               `json:"creators"`                // Metaplex creators and their royalty shares.
+}
+
+// NumericTrait describes how a trait with numeric values is displayed.
+type NumericTrait struct {
+	DisplayType string  `json:"display_type,omitempty"` // "number", "boost_number", "boost_percentage" or "date"; empty for a ranked trait.
+	MaxValue    float64 `json:"max_value,omitempty"`    // Optional upper bound of the value.
+}
+
+// Creator represents a Metaplex creator entitled to a share of the royalties.
+type Creator struct {
+	Address string `json:"address"` // Wallet address.
+	Share   int    `json:"share"`   // Percentage of the royalties, all shares summing up to 100.
+}
+
+// OpenSeaAttribute represents an attribute in the OpenSea metadata standard.
+type OpenSeaAttribute struct {
+	DisplayType string      `json:"display_type,omitempty"`
+	TraitType   string      `json:"trait_type"`
+	Value       interface{} `json:"value"` // A string, or a number for numeric traits.
+	MaxValue    float64     `json:"max_value,omitempty"`
+}
+
+// OpenSeaMetadata represents token metadata in the OpenSea metadata standard.
+type OpenSeaMetadata struct {
+	Name            string             `json:"name"`
+	Description     string             `json:"description"`
+	Image           string             `json:"image"`
+	AnimationURL    string             `json:"animation_url,omitempty"`
+	ExternalURL     string             `json:"external_url,omitempty"`
+	BackgroundColor string             `json:"background_color,omitempty"`
+	Attributes      []OpenSeaAttribute `json:"attributes"`
+}
+
+// ERC721Metadata represents token metadata in the ERC-721 metadata JSON schema,
+// extended with the widely supported attributes list.
+type ERC721Metadata struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Image       string      `json:"image"`
+	Attributes  []Attribute `json:"attributes,omitempty"`
+}
+
+// MetaplexFile represents a file listed in the properties of Metaplex metadata.
+type MetaplexFile struct {
+	URI  string `json:"uri"`
+	Type string `json:"type"`
+}
+
+// MetaplexProperties represents the properties block of Metaplex metadata.
+type MetaplexProperties struct {
+	Files    []MetaplexFile `json:"files"`
+	Category string         `json:"category"`
+	Creators []Creator      `json:"creators"`
+}
+
+// MetaplexMetadata represents token metadata in the Metaplex token metadata standard.
+type MetaplexMetadata struct {
+	Name                 string             `json:"name"`
+	Symbol               string             `json:"symbol"`
+	Description          string             `json:"description"`
+	SellerFeeBasisPoints int                `json:"seller_fee_basis_points"`
+	Image                string             `json:"image"`
+	AnimationURL         string             `json:"animation_url,omitempty"`
+	ExternalURL          string             `json:"external_url,omitempty"`
+	Attributes           []Attribute        `json:"attributes"`
+	Properties           MetaplexProperties `json:"properties"`
+}
+
+// Profiled converts the metadata to the given profile.
+// Returns the metadata itself for the default or an unknown profile.
+func (a *APIResponse) Profiled(profile Profile, opts ProfileOptions) interface{} {
+	switch profile {
+	case ProfileOpenSea:
+		return a.ToOpenSea(opts)
+	case ProfileERC721:
+		return a.ToERC721()
+	case ProfileMetaplex:
+		return a.ToMetaplex(opts)
+	default:
+		return a
+	}
+}
+
+// ToOpenSea converts the metadata to the OpenSea metadata standard.
+// Traits listed in opts.NumericTraits are exported as numbers when their value parses as one.
+func (a *APIResponse) ToOpenSea(opts ProfileOptions) OpenSeaMetadata {
+	result := OpenSeaMetadata{
+		Name:            a.Name,
+		Description:     a.Description,
+		Image:           a.Image,
+		AnimationURL:    a.AnimationURL,
+		ExternalURL:     a.externalURL(opts),
+		BackgroundColor: strings.TrimPrefix(opts.BackgroundColor, "#"),
+		Attributes:      []OpenSeaAttribute{},
+	}
+
+	for _, attr := range a.Attributes {
+		attribute := OpenSeaAttribute{
+			TraitType: attr.TraitType,
+			Value:     attr.Value,
+		}
+
+		if numeric, ok := opts.NumericTraits[attr.TraitType]; ok {
+			if value, err := strconv.ParseFloat(attr.Value, 64); err == nil {
+				attribute.Value = value
+				attribute.DisplayType = numeric.DisplayType
+				attribute.MaxValue = numeric.MaxValue
+			}
+		}
+
+		result.Attributes = append(result.Attributes, attribute)
+	}
+
+	return result
+}
+
+// ToERC721 converts the metadata to the ERC-721 metadata JSON schema.
+func (a *APIResponse) ToERC721() ERC721Metadata {
+	return ERC721Metadata{
+		Name:        a.Name,
+		Description: a.Description,
+		Image:       a.Image,
+		Attributes:  a.Attributes,
+	}
+}
+
+// ToMetaplex converts the metadata to the Metaplex token metadata standard.
+func (a *APIResponse) ToMetaplex(opts ProfileOptions) MetaplexMetadata {
+	result := MetaplexMetadata{
+		Name:                 a.Name,
+		Symbol:               opts.Symbol,
+		Description:          a.Description,
+		SellerFeeBasisPoints: opts.SellerFeeBasisPoints,
+		Image:                a.Image,
+		AnimationURL:         a.AnimationURL,
+		ExternalURL:          a.externalURL(opts),
+		Attributes:           a.Attributes,
+		Properties: MetaplexProperties{
+			Files:    []MetaplexFile{{URI: a.Image, Type: mimeType(a.Image)}},
+			Category: "image",
+			Creators: opts.Creators,
+		},
+	}
+
+	if a.AnimationURL != "" {
+		result.Properties.Files = append(result.Properties.Files, MetaplexFile{
+			URI:  a.AnimationURL,
+			Type: mimeType(a.AnimationURL),
+		})
+		result.Properties.Category = "video"
+	}
+
+	if result.Attributes == nil {
+		result.Attributes = []Attribute{}
+	}
+	if result.Properties.Creators == nil {
+		result.Properties.Creators = []Creator{}
+	}
+
+	return result
+}
+
+// externalURL returns the external URL of the token, preferring the one in the metadata
+// over the template of the options.
+func (a *APIResponse) externalURL(opts ProfileOptions) string {
+	if a.ExternalURL != "" {
+		return a.ExternalURL
+	}
+	return strings.ReplaceAll(opts.ExternalURL, "{id}", strconv.Itoa(a.TokenID))
+}
+
+// mimeType guesses the MIME type of a file from the extension of its URI.
+func mimeType(uri string) string {
+	switch {
+	case strings.HasSuffix(uri, ".png"):
+		return "image/png"
+	case strings.HasSuffix(uri, ".jpg"), strings.HasSuffix(uri, ".jpeg"):
+		return "image/jpeg"
+	case strings.HasSuffix(uri, ".gif"):
+		return "image/gif"
+	case strings.HasSuffix(uri, ".mp4"):
+		return "video/mp4"
+	default:
+		return "application/octet-stream"
+	}
+}
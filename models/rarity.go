@@ -35,6 +35,17 @@ func (s Rarity) IsValid() bool {
 	}
 }
 
+// IsOneOfOne checks if the Rarity belongs to a hand-made token ("1 Of 1" or one of the "?" colors),
+// whose traits are not generated.
+func (s Rarity) IsOneOfOne() bool {
+	switch s {
+	case ONE_OF_ONE, UNKNOWN_COLOR1, UNKNOWN_COLOR2, UNKNOWN_COLOR3:
+		return true
+	default:
+		return false
+	}
+}
+
 // IsInvalid checks if the Rarity is invalid by negating IsValid.
 func (s Rarity) IsInvalid() bool {
 	return !s.IsValid()
@@ -44,3 +55,11 @@ func (s Rarity) IsInvalid() bool {
 func (s Rarity) String() string {
 	return string(s)
 }
+
+// RarityList returns a slice of all rarities whose traits are generated, excluding the hand-made ones.
+func RarityList() []Rarity {
+	return []Rarity{
+		COMMON, RARE_PURPLE, RARE_ORANGE, RARE_YELLOW, MYTHIC_LAVENDER, MYTHIC_TEAL,
+		ULTRA_BLUE, ULTRA_GREEN, ULTRA_PINK, LEGENDARY_SILVER, LEGENDARY_VIOLET, LEGENDARY_BLUE,
+	}
+}
@@ -29,3 +29,24 @@ func (rl RarityLocked) IsInvalid() bool {
 func (rl RarityLocked) IsValid() bool {
 	return rl == RarityLockedNA || rl == RarityLockedNone || rl == RarityLockedY
 }
+
+// AllowsRarity checks if the item can be used by a token of the given rarity:
+// the rarity must be one of the rarities the item is restricted to, if any,
+// and none of the rarities it is excluded from.
+func (c *Common) AllowsRarity(rarity Rarity) bool {
+	for _, excluded := range c.ExcludedRarities {
+		if excluded == rarity {
+			return false
+		}
+	}
+
+	if len(c.Rarities) == 0 {
+		return true
+	}
+	for _, allowed := range c.Rarities {
+		if allowed == rarity {
+			return true
+		}
+	}
+	return false
+}
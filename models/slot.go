@@ -0,0 +1,332 @@
+package models
+
+// Slot identifies one layer of the final image. Most slots are named after the
+// folder holding their images; the folders shared by two slots get a suffix.
+type Slot string
+
+// Constants representing every layer slot.
+const (
+	SlotBG                      Slot = "BACKGROUND"                 // Background
+	SlotBGAccent                Slot = "BACKGROUND ACCENT"          // Background accent
+	SlotDropletsBack            Slot = "DROPLETS (BACK)"            // Back droplet
+	SlotAuraBack                Slot = "AURA (BACK)"                // Back aura
+	SlotTails                   Slot = "TAILS"                      // Tail
+	SlotWings                   Slot = "WINGS"                      // Wings
+	SlotWeaponsBack             Slot = "WEAPONS (BACK)"             // Back weapon
+	SlotDropletsBackTransparent Slot = "DROPLET (BACK TRANSPARENT)" // Transparent back droplet
+	SlotStackableHatsBack       Slot = "HATS (STACKABLE BACK)"      // Back of stackable hat
+	SlotHairBack                Slot = "HAIR (BACK)"                // Back hair
+	SlotBodies                  Slot = "BODIES"                     // Body
+	SlotFacegears               Slot = "FACE GEAR"                  // Face gear
+	SlotClothes                 Slot = "CLOTHES"                    // Clothes
+	SlotHands                   Slot = "HANDS"                      // Hands
+	SlotWeaponsFront            Slot = "WEAPONS (FRONT)"            // Front weapon
+	SlotEyes                    Slot = "EYES"                       // Eyes
+	SlotMouths                  Slot = "MOUTH"                      // Mouth
+	SlotNose                    Slot = "NOSE"                       // Nose
+	SlotHair                    Slot = "HAIR"                       // Main hair
+	SlotHats                    Slot = "HATS"                       // Hat
+	SlotHatsEarless             Slot = "HATS (EARLESS)"             // Earless hat
+	SlotStackableHats           Slot = "HATS (STACKABLE)"           // Front of stackable hat
+	SlotElvenEars               Slot = "ELVEN EARS"                 // Elven ears
+	SlotEarrings                Slot = "EARRINGS"                   // Earrings
+	SlotGlasses                 Slot = "GLASSES"                    // Glasses
+	SlotDroplets                Slot = "DROPLETS"                   // Front droplet
+	SlotAuraFront               Slot = "AURA (FRONT)"               // Front aura
+)
+
+// SlotList returns every slot in compositing order, from the bottom layer to the top one.
+func SlotList() []Slot {
+	return []Slot{
+		SlotBG,
+		SlotBGAccent,
+		SlotDropletsBack,
+		SlotAuraBack,
+		SlotTails,
+		SlotWings,
+		SlotWeaponsBack,
+		SlotDropletsBackTransparent,
+		SlotStackableHatsBack,
+		SlotHairBack,
+		SlotBodies,
+		SlotFacegears,
+		SlotClothes,
+		SlotHands,
+		SlotWeaponsFront,
+		SlotEyes,
+		SlotMouths,
+		SlotNose,
+		SlotHair,
+		SlotHats,
+		SlotHatsEarless,
+		SlotStackableHats,
+		SlotElvenEars,
+		SlotEarrings,
+		SlotGlasses,
+		SlotDroplets,
+		SlotAuraFront,
+	}
+}
+
+// IsValid checks if the slot is one of the defined slots.
+func (s Slot) IsValid() bool {
+	for _, slot := range SlotList() {
+		if slot == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInvalid checks if the slot is invalid by negating IsValid.
+func (s Slot) IsInvalid() bool {
+	return !s.IsValid()
+}
+
+// String returns the string representation of the Slot.
+func (s Slot) String() string {
+	return string(s)
+}
+
+// Folder returns the folder, relative to the traits folder, holding the images of the slot.
+func (s Slot) Folder() string {
+	switch s {
+	case SlotHatsEarless:
+		return "HATS"
+	case SlotStackableHatsBack:
+		return "HATS (STACKABLE)"
+	default:
+		return string(s)
+	}
+}
+
+// TraitType returns the metadata trait type the slot is reported under.
+// Returns an empty string for slots that do not emit an attribute.
+func (s Slot) TraitType() string {
+	switch s {
+	case SlotBG, SlotBGAccent, SlotAuraBack, SlotAuraFront:
+		return "Background"
+	case SlotDropletsBack, SlotDropletsBackTransparent, SlotDroplets:
+		return "Rarity"
+	case SlotWings:
+		return "Wings"
+	case SlotWeaponsBack:
+		return "Weapon"
+	case SlotWeaponsFront:
+		return "Weapons"
+	case SlotStackableHatsBack, SlotStackableHats, SlotHats, SlotHatsEarless:
+		return "Hat"
+	case SlotHairBack, SlotHair:
+		return "Hair"
+	case SlotBodies:
+		return "Body"
+	case SlotFacegears:
+		return "Face"
+	case SlotClothes:
+		return "Clothes"
+	case SlotEyes:
+		return "Eyes"
+	case SlotMouths:
+		return "Mouth"
+	case SlotGlasses:
+		return "Glasses"
+	default:
+		return ""
+	}
+}
+
+// Get returns the item selected for the given slot, or nil if the slot is empty.
+func (f FinalTraits) Get(slot Slot) *Common {
+	switch slot {
+	case SlotBG:
+		return f.BG
+	case SlotBGAccent:
+		return f.BGAccent
+	case SlotDropletsBack:
+		return f.Droplets.DataBack
+	case SlotAuraBack:
+		return f.Aura.Back
+	case SlotTails:
+		return f.Tails
+	case SlotWings:
+		return f.Wings
+	case SlotWeaponsBack:
+		return f.Weapons.Back
+	case SlotDropletsBackTransparent:
+		return f.Droplets.DataBackTransparent
+	case SlotStackableHatsBack:
+		return f.StackableHats.DataBack
+	case SlotHairBack:
+		return f.Hairs.HairBack
+	case SlotBodies:
+		return f.Bodies
+	case SlotFacegears:
+		return f.Facegears
+	case SlotClothes:
+		return f.Clothes
+	case SlotHands:
+		return f.Hands
+	case SlotWeaponsFront:
+		return f.Weapons.Front
+	case SlotEyes:
+		return f.Eyes
+	case SlotMouths:
+		return f.Mouths
+	case SlotNose:
+		return f.Nose
+	case SlotHair:
+		return f.Hairs.Hair
+	case SlotHats:
+		return f.Hats.Data
+	case SlotHatsEarless:
+		return f.Hats.DataEarless
+	case SlotStackableHats:
+		return f.StackableHats.DataFront
+	case SlotElvenEars:
+		return f.ElvenEars
+	case SlotEarrings:
+		return f.Earrings
+	case SlotGlasses:
+		return f.Glasses
+	case SlotDroplets:
+		return f.Droplets.DataFront
+	case SlotAuraFront:
+		return f.Aura.Front
+	default:
+		return nil
+	}
+}
+
+// Set stores the item selected for the given slot. A nil item empties the slot.
+func (f *FinalTraits) Set(slot Slot, common *Common) {
+	switch slot {
+	case SlotBG:
+		f.BG = common
+	case SlotBGAccent:
+		f.BGAccent = common
+	case SlotDropletsBack:
+		f.Droplets.DataBack = common
+	case SlotAuraBack:
+		f.Aura.Back = common
+	case SlotTails:
+		f.Tails = common
+	case SlotWings:
+		f.Wings = common
+	case SlotWeaponsBack:
+		f.Weapons.Back = common
+	case SlotDropletsBackTransparent:
+		f.Droplets.DataBackTransparent = common
+	case SlotStackableHatsBack:
+		f.StackableHats.DataBack = common
+	case SlotHairBack:
+		f.Hairs.HairBack = common
+	case SlotBodies:
+		f.Bodies = common
+	case SlotFacegears:
+		f.Facegears = common
+	case SlotClothes:
+		f.Clothes = common
+	case SlotHands:
+		f.Hands = common
+	case SlotWeaponsFront:
+		f.Weapons.Front = common
+	case SlotEyes:
+		f.Eyes = common
+	case SlotMouths:
+		f.Mouths = common
+	case SlotNose:
+		f.Nose = common
+	case SlotHair:
+		f.Hairs.Hair = common
+	case SlotHats:
+		f.Hats.Data = common
+	case SlotHatsEarless:
+		f.Hats.DataEarless = common
+	case SlotStackableHats:
+		f.StackableHats.DataFront = common
+	case SlotElvenEars:
+		f.ElvenEars = common
+	case SlotEarrings:
+		f.Earrings = common
+	case SlotGlasses:
+		f.Glasses = common
+	case SlotDroplets:
+		f.Droplets.DataFront = common
+	case SlotAuraFront:
+		f.Aura.Front = common
+	}
+}
+
+// Candidates returns every item that can be selected for the given slot,
+// before any filtering. Returns nil if the slot's sheet was not loaded.
+func (t Traits) Candidates(slot Slot) []*Common {
+	switch slot {
+	case SlotBG:
+		return commonsData(t.BG)
+	case SlotBGAccent:
+		return commonsData(t.BGAccent)
+	case SlotTails:
+		return commonsData(t.Tails)
+	case SlotWings:
+		return commonsData(t.Wings)
+	case SlotBodies:
+		return commonsData(t.Bodies)
+	case SlotFacegears:
+		return commonsData(t.Facegears)
+	case SlotClothes:
+		return commonsData(t.Clothes)
+	case SlotHands:
+		return commonsData(t.Hands)
+	case SlotEyes:
+		return commonsData(t.Eyes)
+	case SlotMouths:
+		return commonsData(t.Mouths)
+	case SlotNose:
+		return commonsData(t.Nose)
+	case SlotElvenEars:
+		return commonsData(t.ElvenEars)
+	case SlotEarrings:
+		return commonsData(t.Earrings)
+	case SlotGlasses:
+		return commonsData(t.Glasses)
+	}
+
+	switch {
+	case t.Droplets != nil && slot == SlotDroplets:
+		return t.Droplets.Data
+	case t.Droplets != nil && slot == SlotDropletsBack:
+		return t.Droplets.DataBack
+	case t.Droplets != nil && slot == SlotDropletsBackTransparent:
+		return t.Droplets.DataBackTransparent
+	case t.Aura != nil && slot == SlotAuraBack:
+		return t.Aura.Normal
+	case t.Aura != nil && slot == SlotAuraFront:
+		return t.Aura.Front
+	case t.Weapons != nil && slot == SlotWeaponsFront:
+		return t.Weapons.Front
+	case t.Weapons != nil && slot == SlotWeaponsBack:
+		return t.Weapons.Back
+	case t.StackableHats != nil && slot == SlotStackableHats:
+		return t.StackableHats.Data
+	case t.StackableHats != nil && slot == SlotStackableHatsBack:
+		return t.StackableHats.DataBack
+	case t.Hairs != nil && slot == SlotHair:
+		return t.Hairs.Hair
+	case t.Hairs != nil && slot == SlotHairBack:
+		return t.Hairs.HairBack
+	case t.Hats != nil && slot == SlotHats:
+		return t.Hats.Data
+	case t.Hats != nil && slot == SlotHatsEarless:
+		return t.Hats.DataEarless
+	default:
+		return nil
+	}
+}
+
+// commonsData returns the items of a Commons, tolerating a sheet that was not loaded.
+func commonsData(c *Commons) []*Common {
+	if c == nil {
+		return nil
+	}
+	return c.Data
+}
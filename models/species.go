@@ -35,3 +35,8 @@ func (s Specie) IsInvalid() bool {
 func (s Specie) String() string {
 	return string(s)
 }
+
+// SpecieList returns a slice of all valid species except "NA" and none.
+func SpecieList() []Specie {
+	return []Specie{SpecieBeing, SpecieCyborg, SpecieElven, SpecieFeline, SpecieMonkey, SpecieOrigin, SpecieSoul}
+}
@@ -110,18 +110,34 @@ func parse[T any](sheet *xlsx.Sheet, skip []int, stop int, appendData func(*T, *
 				// Column 8: Skipped (No processing)
 				continue
 			case 9:
-				// Column 9: MustInclude (split by comma and store)
+				// Column 9: MustInclude (split by comma and store, skipping empty values)
 				values := strings.Split(cellString, ",")
 				for _, value := range values {
-					data.MustInclude = append(data.MustInclude, strings.Trim(value, " "))
+					if value = strings.Trim(value, " "); value != "" {
+						data.MustInclude = append(data.MustInclude, value)
+					}
 				}
 			case 10:
-				// Column 10: RarityLocked (split, trim, and validate each rarity value)
+				// Column 10: RarityLocked (split by comma; each value is the Y/NA flag, a rarity
+				// the item is restricted to, or a rarity it is excluded from when prefixed by "!")
 				values := strings.Split(cellString, ",")
 				for _, value := range values {
-					data.RarityLocked = models.RarityLocked(strings.Trim(value, " "))
-					if data.RarityLocked.IsInvalid() {
-						panic(fmt.Sprintf("invalid rarity locked: %s", data.RarityLocked))
+					value = strings.Trim(value, " ")
+					if locked := models.RarityLocked(value); locked.IsValid() {
+						if locked != models.RarityLockedNone && !data.RarityLocked.IsY() {
+							data.RarityLocked = locked
+						}
+						continue
+					}
+
+					rarity := models.Rarity(strings.Trim(strings.TrimPrefix(value, "!"), " "))
+					if rarity.IsInvalid() {
+						panic(fmt.Sprintf("invalid rarity locked: %s", value))
+					}
+					if strings.HasPrefix(value, "!") {
+						data.ExcludedRarities = append(data.ExcludedRarities, rarity)
+					} else {
+						data.Rarities = append(data.Rarities, rarity)
 					}
 				}
 			case 11:
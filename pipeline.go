@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"generator/config"
+	"generator/generator"
+	"generator/models"
+	"generator/storage"
+	"generator/utils"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// TokenSpec is the outcome of the selection stage: everything the compositing
+// and encoding stages need to render and publish a single token.
+type TokenSpec struct {
+	TokenID    int                 `json:"token_id"`
+	Layers     []TraitData         `json:"layers"`
+	Metadata   *models.APIResponse `json:"metadata"`
+	Duplicate  bool                `json:"duplicate,omitempty"`  // Set when no unique combination was found.
+	Violations []string            `json:"violations,omitempty"` // Compatibility rules broken when no valid combination was found.
+	OneOfOne   bool                `json:"one_of_one,omitempty"` // Set for hand-made tokens, which have no layers.
+	Artwork    string              `json:"artwork,omitempty"`    // Pre-made image of a hand-made token, if any.
+
+	// Values decided during selection, recorded so the token can be analysed and regenerated.
+	Category models.Category `json:"category,omitempty"`
+	Gender   models.Gender   `json:"gender,omitempty"`
+	HasHair  *bool           `json:"has_hair,omitempty"`
+
+	Source utils.SourceKind `json:"source,omitempty"` // Random source the token was picked with; empty for runs older than the recorded sources, which replay with sha1-legacy.
+}
+
+// newTokenSpec builds the spec of a token from its selected traits. The
+// category, gender and hair are also written to the metadata properties when
+// the configuration asks for it.
+func newTokenSpec(tokenID int, final models.FinalTraits, layers []TraitData, metadata *models.APIResponse) TokenSpec {
+	spec := TokenSpec{
+		TokenID:  tokenID,
+		Layers:   layers,
+		Metadata: metadata,
+		Category: final.Category,
+		Gender:   final.Gender,
+	}
+
+	// Without a gender the selection did not run, so nothing else was decided.
+	if final.Gender == "" {
+		return spec
+	}
+
+	hasHair := final.HasHair
+	spec.HasHair = &hasHair
+
+	if config.Get().Properties {
+		metadata.Properties = &models.Properties{
+			Category: final.Category,
+			Gender:   final.Gender,
+			HasHair:  final.HasHair,
+		}
+	}
+
+	return spec
+}
+
+// Paths returns the image paths of the token layers in compositing order.
+func (s TokenSpec) Paths() []string {
+	paths := make([]string, 0, len(s.Layers))
+	for _, layer := range s.Layers {
+		paths = append(paths, baseFolder+layer.Folder+"/"+layer.FileName+".png")
+	}
+	return paths
+}
+
+// renderedToken is a composited token waiting to be encoded and written.
+type renderedToken struct {
+	spec  TokenSpec
+	image *generator.ImageCreator
+}
+
+// runPipeline consumes token specs produced by the selection stage and runs
+// them through the compositing and encoding stages. Each stage has its own
+// pool of workers and is connected to the next one by a bounded queue, so a
+// slow disk never stalls the CPU-bound compositors for long and vice versa.
+// Images and metadata are written to results. runPipeline returns once every
+// spec has been written.
+func runPipeline(specs <-chan TokenSpec, results storage.Storage) {
+	rendered := make(chan renderedToken, queueSize)
+
+	var compositors sync.WaitGroup
+	for i := 0; i < compositeWorkers; i++ {
+		compositors.Add(1)
+		go func() {
+			defer compositors.Done()
+			for spec := range specs {
+				rendered <- compositeToken(spec)
+			}
+		}()
+	}
+
+	var encoders sync.WaitGroup
+	for i := 0; i < encodeWorkers; i++ {
+		encoders.Add(1)
+		go func() {
+			defer encoders.Done()
+			for token := range rendered {
+				encodeToken(results, token)
+			}
+		}()
+	}
+
+	compositors.Wait()
+	close(rendered)
+	encoders.Wait()
+}
+
+// compositeToken stacks the layers of a token into its final image.
+// Hand-made tokens have nothing to composite.
+func compositeToken(spec TokenSpec) renderedToken {
+	if spec.OneOfOne {
+		return renderedToken{spec: spec}
+	}
+
+	g := generator.NewImageCreator(spec.TokenID, spec.Paths())
+
+	g.Process()
+
+	return renderedToken{
+		spec:  spec,
+		image: g,
+	}
+}
+
+// encodeToken PNG-encodes the token image and writes it together with the
+// normalized metadata.
+func encodeToken(results storage.Storage, token renderedToken) {
+	tokenID := token.spec.TokenID
+
+	if token.spec.OneOfOne {
+		writeOneOfOne(results, token.spec)
+		log.Printf("Done processing token %d", tokenID)
+		return
+	}
+
+	writeImage(results, fmt.Sprintf("images/%d.png", tokenID), token.image)
+
+	metadata := token.spec.Metadata
+	metadata.MakeAttributesUnique(config.Get().AttributeOptions())
+	metadata.AnimationURL = ""
+	metadata.Image = fmt.Sprintf("https://ipfs.io/ipfs/REPLACE_ME/%d.jpg", tokenID)
+	writeResult(results, fmt.Sprintf("metadata/%d.json", tokenID), metadata)
+
+	log.Printf("Done processing token %d", tokenID)
+}
+
+// writeOneOfOne publishes a hand-made token. Its metadata is kept as is apart
+// from the attribute normalization, and its image URI is only replaced when
+// the artwork was copied to the results.
+func writeOneOfOne(results storage.Storage, spec TokenSpec) {
+	tokenID := spec.TokenID
+	metadata := spec.Metadata
+
+	if spec.Artwork != "" {
+		if err := copyToStorage(results, spec.Artwork, fmt.Sprintf("images/%d.png", tokenID)); err != nil {
+			log.Printf("Failed to copy artwork of token %d: %s", tokenID, err)
+		} else {
+			metadata.Image = fmt.Sprintf("https://ipfs.io/ipfs/REPLACE_ME/%d.jpg", tokenID)
+		}
+	}
+
+	metadata.MakeAttributesUnique(config.Get().AttributeOptions())
+	writeResult(results, fmt.Sprintf("metadata/%d.json", tokenID), metadata)
+}
+
+// openResults opens the storage generation writes to, as configured.
+func openResults() storage.Storage {
+	results, err := storage.Open(config.Get().Output)
+	if err != nil {
+		log.Fatalf("Failed to open the output storage: %s", err)
+	}
+	return results
+}
+
+// closeResults finishes the storage, such as the end of an archive.
+func closeResults(results storage.Storage) {
+	if err := results.Close(); err != nil {
+		log.Fatalf("Failed to close the output storage: %s", err)
+	}
+}
+
+// writeImage PNG-encodes an image into the named result file.
+func writeImage(results storage.Storage, name string, image *generator.ImageCreator) {
+	w, err := results.Create(name)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %s", name, err)
+	}
+
+	if err := image.Encode(w); err != nil {
+		w.Close()
+		log.Fatalf("Failed to encode %s: %s", name, err)
+	}
+
+	if err := w.Close(); err != nil {
+		log.Fatalf("Failed to write %s: %s", name, err)
+	}
+}
+
+// writeResult writes data as indented JSON into the named result file.
+func writeResult(results storage.Storage, name string, data interface{}) {
+	if err := storage.WriteJSON(results, name, data); err != nil {
+		log.Fatalf("Failed to write %s: %s", name, err)
+	}
+}
+
+// copyToStorage copies the file at src into the named result file.
+func copyToStorage(results storage.Storage, src, name string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := results.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
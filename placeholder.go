@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"generator/config"
+	"generator/models"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// executePlaceholder writes the pre-reveal metadata of the first nrNFTs
+// tokens to output: every token points to the placeholder image and carries
+// no traits. The real metadata is left untouched.
+func executePlaceholder(nrNFTs int, output string) {
+	placeholder := config.Get().Placeholder
+	if placeholder.Image == "" {
+		log.Println("No placeholder image configured")
+	}
+
+	if err := os.MkdirAll(output, 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	for tokenID := 0; tokenID < nrNFTs; tokenID++ {
+		replacer := strings.NewReplacer("{id}", strconv.Itoa(tokenID))
+
+		metadata := models.APIResponse{
+			TokenID:      tokenID,
+			Name:         replacer.Replace(placeholder.Name),
+			Description:  replacer.Replace(placeholder.Description),
+			Image:        replacer.Replace(placeholder.Image),
+			AnimationURL: replacer.Replace(placeholder.AnimationURL),
+			Attributes:   []models.Attribute{},
+		}
+
+		if placeholder.StatusAttribute {
+			metadata.Attributes = append(metadata.Attributes, models.Attribute{
+				TraitType: "Status",
+				Value:     "Unrevealed",
+			})
+		}
+
+		writeToSimpleFile(fmt.Sprintf("%s/%d.json", output, tokenID), metadata)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"generator/parse"
+	"generator/utils"
+	"log"
+)
+
+const (
+	planFile       = "./assets/results/plan.json"        // Per-token trait assignments of a dry run
+	planReportFile = "./assets/results/plan_report.json" // Uniqueness and distribution summary of a dry run
+	manifestFile   = "./assets/results/manifest.json"    // Per-token trait assignments of a generation run
+)
+
+// PlanReport summarizes the outcome of a dry run.
+type PlanReport struct {
+	Tokens       int                       `json:"tokens"`       // Number of planned tokens.
+	Duplicates   []int                     `json:"duplicates"`   // Tokens left without a unique combination.
+	Violations   map[int][]string          `json:"violations"`   // Compatibility rules broken, by token left without a valid combination.
+	Failed       map[int]string            `json:"failed"`       // Tokens no selection run completed for, left out of the plan, with the error.
+	Distribution map[string]map[string]int `json:"distribution"` // Occurrences of every trait, by folder.
+}
+
+// executePlan runs the selection stage for the whole collection without
+// rendering anything. It writes the trait assignment of every token to
+// output, plus a report with the uniqueness check and the trait distribution.
+func executePlan(nrNFTs int, output string) {
+	tr := parse.Do()
+
+	if nrNFTs > max_NFTS {
+		nrNFTs = max_NFTS
+	}
+
+	specs := make([]TokenSpec, 0, nrNFTs)
+	failed := make(map[int]string)
+	for tokenID := 0; tokenID < nrNFTs; tokenID++ {
+		spec, err := processToken(nil, tr.Copy(), tokenID)
+		if err != nil {
+			log.Printf("Skipping token %d: %s", tokenID, err)
+			failed[tokenID] = err.Error()
+			continue
+		}
+		specs = append(specs, spec)
+	}
+
+	utils.SetRandomizer(true)
+
+	report := newPlanReport(specs)
+	report.Failed = failed
+
+	writeToSimpleFile(output, specs)
+	writeToSimpleFile(planReportFile, report)
+
+	log.Printf("Planned %d tokens, %d duplicates, %d breaking the rules, %d failed",
+		report.Tokens, len(report.Duplicates), len(report.Violations), len(report.Failed))
+}
+
+// newPlanReport builds the report of a dry run from the planned tokens.
+func newPlanReport(specs []TokenSpec) PlanReport {
+	report := PlanReport{
+		Tokens:       len(specs),
+		Duplicates:   []int{},
+		Violations:   make(map[int][]string),
+		Failed:       make(map[int]string),
+		Distribution: traitDistribution(specs),
+	}
+
+	for _, spec := range specs {
+		if spec.Duplicate {
+			report.Duplicates = append(report.Duplicates, spec.TokenID)
+		}
+		if len(spec.Violations) > 0 {
+			report.Violations[spec.TokenID] = spec.Violations
+		}
+	}
+
+	return report
+}
+
+// traitDistribution counts the occurrences of every trait, by folder, over
+// the layers of the given tokens.
+func traitDistribution(specs []TokenSpec) map[string]map[string]int {
+	distribution := make(map[string]map[string]int)
+
+	for _, spec := range specs {
+		for _, layer := range spec.Layers {
+			if distribution[layer.Folder] == nil {
+				distribution[layer.Folder] = make(map[string]int)
+			}
+			distribution[layer.Folder][layer.Name]++
+		}
+	}
+
+	return distribution
+}
@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"generator/models"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// The filters below are the rules of Process that only depend on the token
+// itself (its specie, gender, category and rarity), not on the other picks.
+// They are shared with Pool so the feasibility analysis sees the same pools.
+
+// dropletsOfRarity keeps the droplets of the token rarity.
+func dropletsOfRarity(f models.FinalTraits, data []*models.Common) []*models.Common {
+	return lo.Filter(data, func(droplet *models.Common, i int) bool {
+		return models.Rarity(droplet.OpenSeaTraitValue) == f.Rarity
+	})
+}
+
+// notExcludedForSpecie drops the items whose MustNotInclude names the token specie.
+func notExcludedForSpecie(f models.FinalTraits, data []*models.Common) []*models.Common {
+	return lo.Filter(data, func(common *models.Common, i int) bool {
+		return !lo.Contains(common.MustNotInclude, f.Specie.String())
+	})
+}
+
+// allowedForSpecie keeps the items the token specie allows, except that
+// Origins only keep the items locked to Origins.
+func allowedForSpecie(f models.FinalTraits, data []*models.Common) []*models.Common {
+	return lo.Filter(data, func(common *models.Common, i int) bool {
+		return common.AllowsSpecie(f.Specie) &&
+			(f.Specie != models.SpecieOrigin || lo.Contains(common.SpeciesLocked, models.SpecieOrigin))
+	})
+}
+
+// felineOnly keeps, for felines, only the items locked to felines.
+func felineOnly(f models.FinalTraits, data []*models.Common) []*models.Common {
+	return lo.Filter(data, func(common *models.Common, i int) bool {
+		return f.Specie != models.SpecieFeline ||
+			lo.Contains(common.SpeciesLocked, models.SpecieFeline)
+	})
+}
+
+// notExcludedForFeline drops, for felines, the items whose MustNotInclude names felines.
+func notExcludedForFeline(f models.FinalTraits, data []*models.Common) []*models.Common {
+	return lo.Filter(data, func(common *models.Common, i int) bool {
+		return f.Specie != models.SpecieFeline ||
+			!lo.Contains(common.MustNotInclude, models.SpecieFeline.String())
+	})
+}
+
+// originBodies keeps, for Origins of a color rarity, the bodies of the droplet color.
+func originBodies(f models.FinalTraits, data []*models.Common) []*models.Common {
+	if f.Specie != models.SpecieOrigin || f.Rarity == models.COMMON {
+		return data
+	}
+
+	color := strings.Split(f.Droplets.DataFront.OpenSeaTraitValue, " ")[1]
+	return lo.Filter(data, func(body *models.Common, i int) bool {
+		return strings.Contains(body.OpenSeaTraitValue, color)
+	})
+}
+
+// hairsForSpecie applies the specie rules of the hair: felines only get feline
+// hair, and elves get neither feline nor soul hair, nor any hair with glasses.
+func hairsForSpecie(f models.FinalTraits, data []*models.Common) []*models.Common {
+	data = felineOnly(f, data)
+	data = lo.Filter(data, func(common *models.Common, i int) bool {
+		return f.Specie != models.SpecieElven ||
+			!lo.Contains(common.MustNotInclude, f.Specie.String()) && f.Glasses == nil
+	})
+	if f.Specie == models.SpecieFeline {
+		data = lo.Filter(data, func(common *models.Common, i int) bool {
+			return lo.Contains(common.SpeciesLocked, models.SpecieFeline)
+		})
+	} else if f.Specie == models.SpecieElven {
+		data = lo.Filter(data, func(common *models.Common, i int) bool {
+			return !lo.Contains(common.SpeciesLocked, models.SpecieFeline) &&
+				!lo.Contains(common.SpeciesLocked, models.SpecieSoul)
+		})
+	}
+	return data
+}
+
+// earringsForSpecie applies the specie rules of the earrings.
+func earringsForSpecie(f models.FinalTraits, data []*models.Common) []*models.Common {
+	return lo.Filter(data, func(common *models.Common, i int) bool {
+		return (f.Specie == models.SpecieFeline && lo.Contains(common.SpeciesLocked, models.SpecieFeline)) ||
+			(f.Specie != models.SpecieFeline && f.Specie != models.SpecieElven) &&
+				(f.Specie == models.SpecieElven && lo.Contains(common.SpeciesLocked, models.SpecieElven))
+	})
+}
@@ -0,0 +1,186 @@
+package processor
+
+import (
+	"generator/models"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// PickedSlots returns the slots Process picks from, in the order it picks
+// them. The other slots are derived from these picks.
+func PickedSlots() []models.Slot {
+	return []models.Slot{
+		models.SlotDroplets,
+		models.SlotBG,
+		models.SlotBGAccent,
+		models.SlotAuraBack,
+		models.SlotWings,
+		models.SlotWeaponsFront,
+		models.SlotBodies,
+		models.SlotHats,
+		models.SlotFacegears,
+		models.SlotHatsEarless,
+		models.SlotEyes,
+		models.SlotGlasses,
+		models.SlotNose,
+		models.SlotHair,
+		models.SlotClothes,
+		models.SlotMouths,
+		models.SlotEarrings,
+		models.SlotStackableHats,
+	}
+}
+
+// mandatory reports whether Process must fill the slot when it picks it, given
+// the picks of f so far. Process draws a mandatory slot without its NA weight.
+func mandatory(f models.FinalTraits, slot models.Slot) bool {
+	switch slot {
+	case models.SlotDroplets, models.SlotBodies, models.SlotHatsEarless:
+		return true
+	case models.SlotEyes:
+		return f.Specie == models.SpecieOrigin
+	case models.SlotStackableHats:
+		// The stackable hat stands in for the missing hair and hat.
+		return f.Hairs.Hair == nil && f.Hats.Data == nil
+	}
+	return false
+}
+
+// IsMandatory reports whether Process must fill the slot for some tokens of
+// the segment of c.Final, by the rules of mandatory. hairChance is the chance
+// of having hair of the segment, in percent.
+func IsMandatory(c *models.Traits, slot models.Slot, hairChance float64) bool {
+	if slot != models.SlotStackableHats {
+		return mandatory(c.Final, slot)
+	}
+
+	if c.Final.Specie == models.SpecieMonkey {
+		hairChance = 0
+	}
+
+	// A token without hair may get a hat, a token with hair never does.
+	return hairChance < 100 && canLack(c, models.SlotHats) ||
+		hairChance > 0 && canLack(c, models.SlotHair)
+}
+
+// canLack reports whether a token of the segment of c.Final can end up
+// without a hat or without hair: the slot is not picked, its pool is empty or
+// it can draw NA.
+func canLack(c *models.Traits, slot models.Slot) bool {
+	data, ok := Pool(c, slot)
+	if !ok || len(data) == 0 {
+		return true
+	}
+
+	var na *models.Common
+	switch slot {
+	case models.SlotHats:
+		// Beings only get a hat with a being body.
+		if c.Final.Specie == models.SpecieBeing {
+			bodies, _ := Pool(c, models.SlotBodies)
+			if lo.SomeBy(bodies, func(common *models.Common) bool {
+				return !strings.Contains(strings.ToLower(common.OpenSeaTraitValue), "being")
+			}) {
+				return true
+			}
+		}
+		if c.Hats != nil {
+			na = c.Hats.NA
+		}
+	case models.SlotHair:
+		if c.Hairs != nil {
+			na = c.Hairs.NA
+		}
+	}
+
+	return na != nil && na.Distribution.GetRat().Sign() > 0
+}
+
+// Pool returns the items Process can pick for a slot, given the specie,
+// gender, category and rarity of c.Final, rarity locks included. Only the rules that depend on these
+// are applied, so the pool is an upper bound: the picks of the other slots can
+// still narrow it down. Returns false when Process never picks the slot for
+// the specie.
+func Pool(c *models.Traits, slot models.Slot) ([]*models.Common, bool) {
+	f := c.Final
+	specie := f.Specie
+
+	var droplets []*models.Common
+	if c.Droplets != nil {
+		droplets = dropletsOfRarity(f, c.Droplets.Data)
+	}
+	if slot == models.SlotDroplets {
+		return first(droplets), true
+	}
+
+	// Every other filter may depend on the droplet color.
+	if f.Droplets.DataFront == nil {
+		if len(droplets) == 0 {
+			return nil, true
+		}
+		f.Droplets.DataFront = droplets[0]
+	}
+
+	data := c.Candidates(slot)
+
+	switch slot {
+	case models.SlotBG:
+		data = f.FilterByRarity(notExcludedForSpecie(f, data))
+	case models.SlotBGAccent:
+		data = f.FilterByRarity(data)
+	case models.SlotBodies:
+		data = f.DefaultFilter(data)
+		if specie == models.SpecieOrigin {
+			return first(originBodies(f, data)), true
+		}
+	case models.SlotHats:
+		data = allowedForSpecie(f, felineOnly(f, f.DefaultFilter(data)))
+	case models.SlotFacegears:
+		if specie == models.SpecieMonkey || specie == models.SpecieCyborg {
+			return nil, false
+		}
+		data = notExcludedForFeline(f, f.DefaultFilter(data))
+	case models.SlotHatsEarless:
+		if specie != models.SpecieBeing {
+			return nil, false
+		}
+		data = f.DefaultFilter(data)
+	case models.SlotEyes, models.SlotClothes, models.SlotMouths:
+		data = allowedForSpecie(f, f.DefaultFilter(data))
+	case models.SlotHair:
+		if specie == models.SpecieOrigin || specie == models.SpecieMonkey {
+			return nil, false
+		}
+		data = hairsForSpecie(f, f.DefaultFilter(data))
+	case models.SlotEarrings:
+		if specie == models.SpecieFeline {
+			return nil, false
+		}
+		data = earringsForSpecie(f, f.DefaultFilter(data))
+	case models.SlotStackableHats:
+		if specie == models.SpecieFeline {
+			return nil, false
+		}
+		data = f.DefaultFilter(data)
+	default:
+		data = f.DefaultFilter(data)
+	}
+
+	return pickable(data), true
+}
+
+// pickable drops the items without weight, which are never picked.
+func pickable(data []*models.Common) []*models.Common {
+	return lo.Filter(data, func(common *models.Common, i int) bool {
+		return common.Distribution.GetRat().Sign() > 0
+	})
+}
+
+// first keeps the first item only, the one Process takes when it does not draw.
+func first(data []*models.Common) []*models.Common {
+	if len(data) > 1 {
+		return data[:1]
+	}
+	return data
+}
@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"generator/models"
+	"testing"
+)
+
+// poolItem returns an item any male COOL token of the given species can get.
+func poolItem(value, distribution string, species ...models.Specie) *models.Common {
+	return &models.Common{
+		FileName:          value,
+		OpenSeaTraitValue: value,
+		Gender:            models.GenderUnisex,
+		Category:          []models.Category{models.CategoryCool},
+		SpeciesLocked:     species,
+		Distribution:      models.Distribution(distribution),
+	}
+}
+
+// poolTraits returns the traits of a male COOL Common token of the specie.
+func poolTraits(specie models.Specie, hats, hairs []*models.Common, hatNA, hairNA string) *models.Traits {
+	c := &models.Traits{
+		Droplets: &models.Droplets{Data: []*models.Common{poolItem(models.COMMON.String(), "100%")}},
+		Bodies:   &models.Commons{Data: []*models.Common{poolItem("Body", "100%", specie)}},
+		Hats:     &models.Hats{Data: hats, NA: poolItem("NA", hatNA)},
+		Hairs:    &models.Hairs{Hair: hairs, NA: poolItem("NA", hairNA)},
+	}
+	c.Final.Specie = specie
+	c.Final.Rarity = models.COMMON
+	c.Final.Gender = models.GenderMale
+	c.Final.Category = models.CategoryCool
+	return c
+}
+
+func TestIsMandatory(t *testing.T) {
+	hats := []*models.Common{poolItem("Cap", "100%", models.SpecieNone)}
+	hairs := []*models.Common{poolItem("Bun", "100%", models.SpecieNone)}
+
+	tests := []struct {
+		name       string
+		traits     *models.Traits
+		slot       models.Slot
+		hairChance float64
+		expected   bool
+	}{
+		{"bodies", poolTraits(models.SpecieCyborg, hats, hairs, "", ""), models.SlotBodies, 50, true},
+		{"optional slot", poolTraits(models.SpecieCyborg, hats, hairs, "", ""), models.SlotNose, 50, false},
+		{"eyes", poolTraits(models.SpecieCyborg, hats, hairs, "", ""), models.SlotEyes, 50, false},
+		{"origin eyes", poolTraits(models.SpecieOrigin, hats, hairs, "", ""), models.SlotEyes, 50, true},
+		{"earless hat", poolTraits(models.SpecieBeing, hats, hairs, "", ""), models.SlotHatsEarless, 50, true},
+		{"hair and hat always picked", poolTraits(models.SpecieCyborg, hats, hairs, "", ""), models.SlotStackableHats, 50, false},
+		{"hat can be NA", poolTraits(models.SpecieCyborg, hats, hairs, "10%", ""), models.SlotStackableHats, 50, true},
+		{"hat NA without hatless tokens", poolTraits(models.SpecieCyborg, hats, hairs, "10%", ""), models.SlotStackableHats, 100, false},
+		{"hair can be NA", poolTraits(models.SpecieCyborg, hats, hairs, "", "10%"), models.SlotStackableHats, 50, true},
+		{"hair NA without hairy tokens", poolTraits(models.SpecieCyborg, hats, hairs, "", "10%"), models.SlotStackableHats, 0, false},
+		{"empty hat pool", poolTraits(models.SpecieCyborg, nil, hairs, "", ""), models.SlotStackableHats, 50, true},
+		{"origins get no hair", poolTraits(models.SpecieOrigin, hats, hairs, "", ""), models.SlotStackableHats, 50, true},
+		{"monkeys get no hair", poolTraits(models.SpecieMonkey, hats, hairs, "", ""), models.SlotStackableHats, 100, false},
+		{"beings without a being body", poolTraits(models.SpecieBeing, hats, hairs, "", ""), models.SlotStackableHats, 50, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsMandatory(test.traits, test.slot, test.hairChance); got != test.expected {
+				t.Errorf("IsMandatory(%s) = %v, expected %v", test.slot, got, test.expected)
+			}
+		})
+	}
+}
+
+// TestMandatoryStackableHat checks that the stackable hat is forced exactly
+// when the token has neither hair nor hat.
+func TestMandatoryStackableHat(t *testing.T) {
+	var f models.FinalTraits
+	if !mandatory(f, models.SlotStackableHats) {
+		t.Error("a token without hair and hat does not need a stackable hat")
+	}
+
+	f.Hairs.Hair = poolItem("Bun", "100%")
+	if mandatory(f, models.SlotStackableHats) {
+		t.Error("a token with hair needs a stackable hat")
+	}
+
+	f.Hairs.Hair = nil
+	f.Hats.Data = poolItem("Cap", "100%")
+	if mandatory(f, models.SlotStackableHats) {
+		t.Error("a token with a hat needs a stackable hat")
+	}
+}
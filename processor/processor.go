@@ -9,10 +9,12 @@ import (
 	"github.com/samber/lo"
 )
 
-func Process(r *utils.Randomizer, c *models.Traits) {
-	c.Droplets.Data = lo.Filter(c.Droplets.Data, func(droplet *models.Common, i int) bool {
-		return models.Rarity(droplet.OpenSeaTraitValue) == c.Final.Rarity
-	})
+// Process picks the traits of a token according to the compatibility rules,
+// storing them in c.Final. Every pick is labeled with its slot, so a selection
+// that fails can be revised by backtracking. Returns a *DeadEndError when the
+// choices made leave a mandatory slot without any candidate.
+func Process(r *utils.Randomizer, c *models.Traits) error {
+	c.Droplets.Data = dropletsOfRarity(c.Final, c.Droplets.Data)
 	if len(c.Droplets.Data) > 0 {
 		c.Final.Droplets.DataFront = c.Droplets.Data[0]
 
@@ -21,28 +23,28 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 		c.Final.Droplets.DataBack = ExtractByTraitValue(c.Droplets.DataBack, traitValue)
 		c.Final.Droplets.DataBackTransparent = ExtractByTraitValue(c.Droplets.DataBackTransparent, traitValue)
 	} else {
-		panic("no droplets found")
+		// The droplet only depends on the rarity, which no choice can change.
+		return &DeadEndError{Slot: models.SlotDroplets}
 	}
 
-	originBGData := c.BG.Data
-	c.BG.Data = lo.Filter(originBGData, func(common *models.Common, i int) bool {
-		return !lo.Contains(common.MustNotInclude, c.Final.Specie.String())
-	})
-	if picked := r.Random(c.BG.Data, c.BG.NA); picked != nil {
+	c.BG.Data = notExcludedForSpecie(c.Final, c.BG.Data)
+	c.BG.Data = c.Final.FilterByRarity(c.BG.Data)
+	if picked := pick(r, c, models.SlotBG, c.BG.Data, c.BG.NA); picked != nil {
 		c.Final.BG = picked
 	} else {
 		fmt.Printf("no bg found for token %d and %s\n", c.Final.Metadata.TokenID, r.Seed)
 	}
 
 	c.BG.Data = c.Final.DefaultFilter(c.BG.Data, models.FilterGender, models.FilterCategory)
-	if picked := r.Random(c.BGAccent.Data, c.BGAccent.NA); picked != nil {
+	c.BGAccent.Data = c.Final.FilterByRarity(c.BGAccent.Data)
+	if picked := pick(r, c, models.SlotBGAccent, c.BGAccent.Data, c.BGAccent.NA); picked != nil {
 		c.Final.BGAccent = picked
 	} else {
 		fmt.Println("no bg accent found")
 	}
 
 	c.Aura.Normal = c.Final.DefaultFilter(c.Aura.Normal)
-	if picked := r.Random(c.Aura.Normal, c.Aura.NA); picked != nil {
+	if picked := pick(r, c, models.SlotAuraBack, c.Aura.Normal, c.Aura.NA); picked != nil {
 		c.Final.Aura.Back = picked
 
 		if picked.Combined.Bool() {
@@ -51,12 +53,12 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 	}
 
 	c.Wings.Data = c.Final.DefaultFilter(c.Wings.Data)
-	if picked := r.Random(c.Wings.Data, c.Wings.NA); picked != nil {
+	if picked := pick(r, c, models.SlotWings, c.Wings.Data, c.Wings.NA); picked != nil {
 		c.Final.Wings = picked
 	}
 
 	c.Weapons.Front = c.Final.DefaultFilter(c.Weapons.Front)
-	if picked := r.Random(c.Weapons.Front, c.Weapons.NA); picked != nil {
+	if picked := pick(r, c, models.SlotWeaponsFront, c.Weapons.Front, c.Weapons.NA); picked != nil {
 		c.Final.Weapons.Front = picked
 
 		if picked.Combined.Bool() {
@@ -66,19 +68,19 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 
 	c.Bodies.Data = c.Final.DefaultFilter(c.Bodies.Data)
 	if c.Final.Specie == models.SpecieOrigin {
-		if c.Final.Rarity != models.COMMON {
-			color := strings.Split(c.Final.Droplets.DataFront.OpenSeaTraitValue, " ")[1]
-			c.Bodies.Data = lo.Filter(c.Bodies.Data, func(body *models.Common, i int) bool {
-				return strings.Contains(body.OpenSeaTraitValue, color)
-			})
-			c.Final.Bodies = c.Bodies.Data[0]
-		} else if len(c.Bodies.Data) > 0 {
+		c.Bodies.Data = originBodies(c.Final, c.Bodies.Data)
+		if len(c.Bodies.Data) > 0 {
 			c.Final.Bodies = c.Bodies.Data[0]
 		}
-	} else if picked := r.Random(c.Bodies.Data, c.Bodies.NA); picked != nil {
+	} else if picked := pick(r, c, models.SlotBodies, c.Bodies.Data, c.Bodies.NA); picked != nil {
 		c.Final.Bodies = picked
-	} else {
-		panic("no body found")
+	}
+
+	if c.Final.Bodies == nil {
+		return &DeadEndError{
+			Slot:   models.SlotBodies,
+			Causes: []string{models.DerivedGender.String(), models.DerivedCategory.String()},
+		}
 	}
 
 	if c.Final.Specie == models.SpecieElven {
@@ -97,20 +99,9 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 		originHatData := c.Hats.Data
 
 		c.Hats.Data = c.Final.DefaultFilter(originHatData)
-		c.Hats.Data = lo.Filter(c.Hats.Data, func(common *models.Common, i int) bool {
-			return c.Final.Specie != models.SpecieFeline ||
-				lo.Contains(common.SpeciesLocked, models.SpecieFeline)
-		})
-		c.Hats.Data = lo.Filter(c.Hats.Data, func(common *models.Common, i int) bool {
-			case1 := lo.Contains(common.SpeciesLocked, c.Final.Specie)
-			case2 := common.RarityLocked.IsY() &&
-				c.Final.Specie != models.SpecieSoul && c.Final.Specie != models.SpecieOrigin
-			case3 :=
-				lo.Contains(common.SpeciesLocked, models.SpecieNone) &&
-					c.Final.Specie != models.SpecieSoul && c.Final.Specie != models.SpecieOrigin
-			return case1 || case2 || case3
-		})
-		if picked := r.Random(c.Hats.Data, c.Hats.NA); picked != nil {
+		c.Hats.Data = felineOnly(c.Final, c.Hats.Data)
+		c.Hats.Data = allowedForSpecie(c.Final, c.Hats.Data)
+		if picked := pick(r, c, models.SlotHats, c.Hats.Data, c.Hats.NA); picked != nil {
 			c.Final.Hats.Data = picked
 
 			skiMask := "Dark Ski Mask"
@@ -142,11 +133,8 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 		if (!checkEarless || c.Final.Hats.DataEarless == nil) &&
 			(!checkNose || c.Final.Nose == nil) {
 			c.Facegears.Data = c.Final.DefaultFilter(c.Facegears.Data)
-			c.Facegears.Data = lo.Filter(c.Facegears.Data, func(common *models.Common, i int) bool {
-				return c.Final.Specie != models.SpecieFeline ||
-					!lo.Contains(common.MustNotInclude, models.SpecieFeline.String())
-			})
-			if picked := r.Random(c.Facegears.Data, c.Facegears.NA); picked != nil {
+			c.Facegears.Data = notExcludedForFeline(c.Final, c.Facegears.Data)
+			if picked := pick(r, c, models.SlotFacegears, c.Facegears.Data, c.Facegears.NA); picked != nil {
 				c.Final.Facegears = picked
 			}
 		}
@@ -175,7 +163,7 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 
 				return !lo.Contains([]string{"4B", "5B", "6B", "7B", "8B"}, c.Final.Bodies.FileName)
 			})
-			if picked := r.Random(originalEarlessHats, nil); picked != nil {
+			if picked := pick(r, c, models.SlotHatsEarless, originalEarlessHats, nil); picked != nil {
 				c.Final.Hats.DataEarless = picked
 			}
 		}
@@ -187,21 +175,14 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 	if c.Final.Hats.Data == nil || !lo.Contains(c.Final.Hats.Data.MustNotInclude, "EYES") {
 		originalEyes := c.Eyes.Data
 		c.Eyes.Data = c.Final.DefaultFilter(originalEyes)
-		c.Eyes.Data = lo.Filter(c.Eyes.Data, func(common *models.Common, i int) bool {
-			case1 := lo.Contains(common.SpeciesLocked, c.Final.Specie)
-			case2 := common.RarityLocked.IsY() &&
-				c.Final.Specie != models.SpecieSoul && c.Final.Specie != models.SpecieOrigin
-			case3 := lo.Contains(common.SpeciesLocked, models.SpecieNone) &&
-				c.Final.Specie != models.SpecieSoul && c.Final.Specie != models.SpecieOrigin
-			return case1 || case2 || case3
-		})
+		c.Eyes.Data = allowedForSpecie(c.Final, c.Eyes.Data)
 
 		distributionNA := c.Eyes.NA
-		if c.Final.Specie == models.SpecieOrigin {
+		if mandatory(c.Final, models.SlotEyes) {
 			distributionNA = nil
 		}
 
-		if picked := r.Random(c.Eyes.Data, distributionNA); picked != nil {
+		if picked := pick(r, c, models.SlotEyes, c.Eyes.Data, distributionNA); picked != nil {
 			if !lo.Contains(picked.MustNotInclude, "EARLESS HAT") || c.Final.Hats.DataEarless == nil {
 				c.Final.Eyes = picked
 				if lo.Contains(picked.MustNotInclude, "NOSE") {
@@ -219,18 +200,18 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 
 	if forceGlasses {
 		c.Glasses.Data = c.Final.DefaultFilter(c.Glasses.Data)
-		if picked := r.Random(c.Glasses.Data, c.Glasses.NA); picked != nil {
+		if picked := pick(r, c, models.SlotGlasses, c.Glasses.Data, c.Glasses.NA); picked != nil {
 			c.Final.Glasses = picked
 			excludeNose = true
 			if lo.Contains(picked.MustInclude, "EYES") {
-				c.Final.Eyes = r.Random(c.Eyes.Data, nil)
+				c.Final.Eyes = pick(r, c, models.SlotEyes, c.Eyes.Data, nil)
 			}
 		}
 	}
 
 	if !excludeNose && (c.Final.Hats.Data == nil || !lo.Contains(c.Final.Hats.Data.MustNotInclude, "NOSE")) {
 		c.Nose.Data = c.Final.DefaultFilter(c.Nose.Data)
-		if picked := r.Random(c.Nose.Data, c.Nose.NA); picked != nil {
+		if picked := pick(r, c, models.SlotNose, c.Nose.Data, c.Nose.NA); picked != nil {
 			c.Final.Nose = picked
 		}
 	}
@@ -242,25 +223,8 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 		originalHairs := c.Hairs.Hair
 
 		c.Hairs.Hair = c.Final.DefaultFilter(originalHairs)
-		c.Hairs.Hair = lo.Filter(c.Hairs.Hair, func(common *models.Common, i int) bool {
-			return c.Final.Specie != models.SpecieFeline ||
-				lo.Contains(common.SpeciesLocked, models.SpecieFeline)
-		})
-		c.Hairs.Hair = lo.Filter(c.Hairs.Hair, func(common *models.Common, i int) bool {
-			return c.Final.Specie != models.SpecieElven ||
-				!lo.Contains(common.MustNotInclude, c.Final.Specie.String()) && c.Final.Glasses == nil
-		})
-		if c.Final.Specie == models.SpecieFeline {
-			c.Hairs.Hair = lo.Filter(c.Hairs.Hair, func(common *models.Common, i int) bool {
-				return lo.Contains(common.SpeciesLocked, models.SpecieFeline)
-			})
-		} else if c.Final.Specie == models.SpecieElven {
-			c.Hairs.Hair = lo.Filter(c.Hairs.Hair, func(common *models.Common, i int) bool {
-				return !lo.Contains(common.SpeciesLocked, models.SpecieFeline) &&
-					!lo.Contains(common.SpeciesLocked, models.SpecieSoul)
-			})
-		}
-		if picked := r.Random(c.Hairs.Hair, c.Hairs.NA); picked != nil {
+		c.Hairs.Hair = hairsForSpecie(c.Final, c.Hairs.Hair)
+		if picked := pick(r, c, models.SlotHair, c.Hairs.Hair, c.Hairs.NA); picked != nil {
 			c.Final.Hairs.Hair = picked
 
 			if picked.Combined.Bool() {
@@ -274,24 +238,15 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 	}
 
 	var stackableHatDistribution = c.StackableHats.NA
-	if c.Final.Hairs.Hair == nil && c.Final.Hats.Data == nil {
+	if mandatory(c.Final, models.SlotStackableHats) {
 		forceStackableHat = true
 		stackableHatDistribution = nil
 	}
 
 	originalClothes := c.Clothes.Data
 	c.Clothes.Data = c.Final.DefaultFilter(originalClothes)
-
-	c.Clothes.Data = lo.Filter(c.Clothes.Data, func(common *models.Common, i int) bool {
-		case1 := lo.Contains(common.SpeciesLocked, c.Final.Specie)
-		case2 := common.RarityLocked.IsY() &&
-			c.Final.Specie != models.SpecieSoul && c.Final.Specie != models.SpecieOrigin
-		case3 :=
-			lo.Contains(common.SpeciesLocked, models.SpecieNone) &&
-				c.Final.Specie != models.SpecieSoul && c.Final.Specie != models.SpecieOrigin
-		return case1 || case2 || case3
-	})
-	if picked := r.Random(c.Clothes.Data, c.Clothes.NA); picked != nil {
+	c.Clothes.Data = allowedForSpecie(c.Final, c.Clothes.Data)
+	if picked := pick(r, c, models.SlotClothes, c.Clothes.Data, c.Clothes.NA); picked != nil {
 		c.Final.Clothes = picked
 	}
 
@@ -312,16 +267,11 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 				"Country Road",
 			}, common.OpenSeaTraitValue)
 		})
+		c.Mouths.Data = allowedForSpecie(c.Final, c.Mouths.Data)
 		c.Mouths.Data = lo.Filter(c.Mouths.Data, func(common *models.Common, i int) bool {
-			case1 := lo.Contains(common.SpeciesLocked, c.Final.Specie)
-			case2 := common.RarityLocked.IsY() &&
-				c.Final.Specie != models.SpecieSoul && c.Final.Specie != models.SpecieOrigin
-			case3 := lo.Contains(common.SpeciesLocked, models.SpecieNone) &&
-				c.Final.Specie != models.SpecieSoul && c.Final.Specie != models.SpecieOrigin
-			return (case1 || case2 || case3) &&
-				(!lo.Contains(common.MustNotInclude, "EARLESS HAT") || c.Final.Hats.DataEarless == nil)
+			return !lo.Contains(common.MustNotInclude, "EARLESS HAT") || c.Final.Hats.DataEarless == nil
 		})
-		if picked := r.Random(c.Mouths.Data, c.Mouths.NA); picked != nil {
+		if picked := pick(r, c, models.SlotMouths, c.Mouths.Data, c.Mouths.NA); picked != nil {
 			c.Final.Mouths = picked
 		}
 	}
@@ -332,12 +282,8 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 		(c.Final.Hairs.Hair == nil || !lo.Contains(c.Final.Hairs.Hair.MustNotInclude, "EARRINGS")) {
 
 		c.Earrings.Data = c.Final.DefaultFilter(c.Earrings.Data)
-		c.Earrings.Data = lo.Filter(c.Earrings.Data, func(common *models.Common, i int) bool {
-			return (c.Final.Specie == models.SpecieFeline && lo.Contains(common.SpeciesLocked, models.SpecieFeline)) ||
-				(c.Final.Specie != models.SpecieFeline && c.Final.Specie != models.SpecieElven) &&
-					(c.Final.Specie == models.SpecieElven && lo.Contains(common.SpeciesLocked, models.SpecieElven))
-		})
-		if picked := r.Random(c.Earrings.Data, c.Earrings.NA); picked != nil {
+		c.Earrings.Data = earringsForSpecie(c.Final, c.Earrings.Data)
+		if picked := pick(r, c, models.SlotEarrings, c.Earrings.Data, c.Earrings.NA); picked != nil {
 			c.Final.Earrings = picked
 		}
 	}
@@ -349,7 +295,20 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 
 			// TODO check Goggle Gear Red for SOUL species
 			c.StackableHats.Data = c.Final.DefaultFilter(c.StackableHats.Data)
-			if picked := r.Random(c.StackableHats.Data, stackableHatDistribution); picked != nil {
+			if forceStackableHat && !hasCandidates(c.StackableHats.Data) {
+				return &DeadEndError{
+					Slot: models.SlotStackableHats,
+					Causes: []string{
+						models.DerivedGender.String(),
+						models.DerivedCategory.String(),
+						models.DerivedHasHair.String(),
+						models.SlotHats.String(),
+						models.SlotHatsEarless.String(),
+						models.SlotHair.String(),
+					},
+				}
+			}
+			if picked := pick(r, c, models.SlotStackableHats, c.StackableHats.Data, stackableHatDistribution); picked != nil {
 				if c.Final.Hats.DataEarless == nil || c.Final.Hats.DataEarless.AbleToHaveStackableHat {
 					c.Final.StackableHats.DataFront = picked
 					if picked.Combined.Bool() {
@@ -360,6 +319,7 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 
 			if c.Final.Hairs.Hair != nil &&
 				c.Final.Hairs.Hair.OnlyHaloAndHorns &&
+				c.Final.StackableHats.DataFront != nil &&
 				(lo.Contains(halos, c.Final.StackableHats.DataFront.FileName) ||
 					!lo.Contains(horns, c.Final.StackableHats.DataFront.FileName)) {
 				c.Final.StackableHats.DataFront = nil
@@ -377,6 +337,10 @@ func Process(r *utils.Randomizer, c *models.Traits) {
 			}
 		}
 	}
+
+	include(r, c)
+
+	return nil
 }
 
 var halos = []string{
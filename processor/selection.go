@@ -0,0 +1,127 @@
+package processor
+
+import (
+	"fmt"
+	"generator/models"
+	"generator/utils"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// DeadEndError reports that the choices made so far leave a mandatory slot
+// without any candidate.
+type DeadEndError struct {
+	Slot   models.Slot // Mandatory slot left empty.
+	Causes []string    // Labels of the decisions that can make a candidate available; none if no choice can.
+}
+
+// Error describes the dead end.
+func (e *DeadEndError) Error() string {
+	return fmt.Sprintf("no %s can be picked", e.Slot)
+}
+
+// pick picks an item of a slot, labeling the decisions with the slot. The
+// MustInclude references of the items picked so far are honoured: a slot they
+// name is not left empty, and the items of the values they name are picked
+// over the others.
+func pick(r *utils.Randomizer, c *models.Traits, slot models.Slot, data []*models.Common, na *models.Common) *models.Common {
+	var values []string
+	for _, ref := range references(c.Final) {
+		if slots, ok := slotReference(ref); ok {
+			if lo.Contains(slots, slot) {
+				na = nil
+			}
+		} else {
+			values = append(values, ref)
+		}
+	}
+
+	required := lo.Filter(data, func(common *models.Common, i int) bool {
+		return lo.Contains(values, common.OpenSeaTraitValue)
+	})
+	if hasCandidates(required) {
+		data, na = required, nil
+	}
+
+	r.Label(slot.String())
+	return r.Random(data, na)
+}
+
+// references returns the MustInclude references of the items picked so far.
+func references(f models.FinalTraits) []string {
+	var result []string
+	for _, slot := range models.SlotList() {
+		if common := f.Get(slot); common != nil {
+			for _, ref := range common.MustInclude {
+				if ref = strings.TrimSpace(ref); ref != "" {
+					result = append(result, ref)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// include satisfies the MustInclude references left unmet once every slot was
+// considered, which happens when the slot they name was passed before the item
+// naming it was picked: a slot named is picked from its pool, and a value named
+// is taken from the pool of an empty slot holding it. The references that
+// still cannot be met are reported by Validate.
+func include(r *utils.Randomizer, c *models.Traits) {
+	for _, ref := range references(c.Final) {
+		slots, ok := slotReference(ref)
+		if ok {
+			if lo.ContainsBy(slots, func(slot models.Slot) bool { return c.Final.Get(slot) != nil }) {
+				continue
+			}
+
+			for _, slot := range slots {
+				data, ok := Pool(c, slot)
+				if !ok {
+					continue
+				}
+				if picked := pick(r, c, slot, data, nil); picked != nil {
+					set(c, slot, picked)
+					break
+				}
+			}
+			continue
+		}
+
+		if hasValue(c.Final, ref) {
+			continue
+		}
+
+		for _, slot := range PickedSlots() {
+			if c.Final.Get(slot) != nil {
+				continue
+			}
+
+			data, _ := Pool(c, slot)
+			if common := OptionalExtractByTraitValue(data, ref); common != nil {
+				set(c, slot, common)
+				break
+			}
+		}
+	}
+}
+
+// set stores an item picked for a slot, along with its back layer when it is combined.
+func set(c *models.Traits, slot models.Slot, common *models.Common) {
+	c.Final.Set(slot, common)
+
+	if back, ok := combinedBacks[slot]; ok && common.Combined.Bool() {
+		c.Final.Set(back, ExtractByTraitValue(c.Candidates(back), common.OpenSeaTraitValue))
+	}
+}
+
+// hasCandidates reports whether any item can be picked.
+func hasCandidates(data []*models.Common) bool {
+	for _, common := range data {
+		if common.Distribution.GetRat().Sign() > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+package processor
+
+import (
+	"fmt"
+	"generator/models"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// slotReferences maps the short slot references used in the MustInclude and
+// MustNotInclude columns of the spreadsheet to the slots they name, including
+// the misspellings found in the spreadsheet.
+var slotReferences = map[string][]models.Slot{
+	"NOSE":           {models.SlotNose},
+	"MOUTH":          {models.SlotMouths},
+	"EYES":           {models.SlotEyes},
+	"EARRINGS":       {models.SlotEarrings},
+	"FACEGEAR":       {models.SlotFacegears},
+	"EARLESS":        {models.SlotHatsEarless},
+	"EARLESS HAT":    {models.SlotHatsEarless},
+	"EARLEES HAT":    {models.SlotHatsEarless},
+	"STACKABLE HATS": {models.SlotStackableHats, models.SlotStackableHatsBack},
+}
+
+// specieReferences maps the misspelled species of the MustNotInclude column
+// to the species they name.
+var specieReferences = map[string]models.Specie{
+	"ORGIN": models.SpecieOrigin,
+}
+
+// combinedBacks maps the slots whose combined items come with a second layer
+// to the slot of that layer.
+var combinedBacks = map[models.Slot]models.Slot{
+	models.SlotAuraBack:      models.SlotAuraFront,
+	models.SlotWeaponsFront:  models.SlotWeaponsBack,
+	models.SlotHair:          models.SlotHairBack,
+	models.SlotStackableHats: models.SlotStackableHatsBack,
+}
+
+// CombinedBack returns the slot of the second layer of the combined items of a
+// slot. Returns false when the items of the slot have no second layer.
+func CombinedBack(slot models.Slot) (models.Slot, bool) {
+	back, ok := combinedBacks[slot]
+	return back, ok
+}
+
+// slotReference returns the slots named by a reference of the MustInclude or
+// MustNotInclude columns: a short reference such as "EYES", or a slot name.
+// Returns false when the reference names something else, such as a trait value.
+func slotReference(ref string) ([]models.Slot, bool) {
+	if slots, ok := slotReferences[ref]; ok {
+		return slots, true
+	}
+	if slot := models.Slot(ref); slot.IsValid() {
+		return []models.Slot{slot}, true
+	}
+	return nil, false
+}
+
+// exclusion is a resolved reference of the MustNotInclude column.
+type exclusion struct {
+	specie   models.Specie // Species excluded, if the reference names one.
+	slots    []models.Slot // Slots excluded, or the slots of the item excluded.
+	fileName string        // File name of the item excluded, if the reference names one.
+}
+
+// resolveExclusion resolves a reference of the MustNotInclude column: a
+// species, a slot or group of slots, or the file name of an item, such as
+// "6BG". File names are matched regardless of case, as the spreadsheet writes
+// "16w" for "16W". Returns an error when the reference names nothing.
+func resolveExclusion(traits *models.Traits, ref string) (exclusion, error) {
+	if specie, ok := specieReferences[ref]; ok {
+		return exclusion{specie: specie}, nil
+	}
+	if specie := models.Specie(ref); specie != models.SpecieNone && specie != models.SpecieNA && specie.IsValid() {
+		return exclusion{specie: specie}, nil
+	}
+	if slots, ok := slotReference(ref); ok {
+		return exclusion{slots: slots}, nil
+	}
+
+	var slots []models.Slot
+	for _, slot := range models.SlotList() {
+		if lo.ContainsBy(traits.Candidates(slot), func(common *models.Common) bool {
+			return strings.EqualFold(common.FileName, ref)
+		}) {
+			slots = append(slots, slot)
+		}
+	}
+	if len(slots) == 0 {
+		return exclusion{}, fmt.Errorf("unknown MustNotInclude reference %q", ref)
+	}
+
+	return exclusion{slots: slots, fileName: ref}, nil
+}
+
+// excludes returns the slots of the selected items an exclusion names, if any.
+func (e exclusion) excludes(f models.FinalTraits) []models.Slot {
+	var result []models.Slot
+	for _, slot := range e.slots {
+		common := f.Get(slot)
+		if common != nil && (e.fileName == "" || strings.EqualFold(common.FileName, e.fileName)) {
+			result = append(result, slot)
+		}
+	}
+	return result
+}
+
+// speciesFiltered lists the slots whose items Process filters by species; the
+// other slots are picked regardless of the species lock, or follow another item.
+var speciesFiltered = func() map[models.Slot]bool {
+	result := make(map[models.Slot]bool)
+	for _, slot := range PickedSlots() {
+		result[slot] = true
+	}
+	delete(result, models.SlotDroplets)
+	delete(result, models.SlotBG)
+	delete(result, models.SlotBGAccent)
+	return result
+}()
+
+// Violation is a compatibility rule broken by a set of selected traits.
+type Violation struct {
+	Slots   []models.Slot // Slots of the items breaking the rule.
+	Message string        // Description of the broken rule.
+}
+
+// Error describes the broken rule.
+func (v *Violation) Error() string {
+	return v.Message
+}
+
+// violation returns a *Violation of the given slots.
+func violation(slots []models.Slot, format string, args ...interface{}) error {
+	return &Violation{Slots: slots, Message: fmt.Sprintf(format, args...)}
+}
+
+// Validate checks a set of selected traits against the compatibility rules of
+// the spreadsheet and returns every violated rule, as *Violation errors. It
+// runs on the traits picked by Process, as well as on traits that were not,
+// such as hand-curated tokens. The MustNotInclude references are resolved
+// against traits; a reference naming nothing is returned as a plain error.
+func Validate(traits *models.Traits, f models.FinalTraits) []error {
+	var errs []error
+
+	for _, slot := range models.SlotList() {
+		common := f.Get(slot)
+		if common == nil {
+			continue
+		}
+
+		if f.Gender != "" && common.Gender != "" &&
+			common.Gender != models.GenderUnisex && common.Gender != models.GenderNA &&
+			common.Gender != f.Gender {
+			errs = append(errs, violation([]models.Slot{slot}, "%s %q is for gender %s, token is %s",
+				slot, common.OpenSeaTraitValue, common.Gender, f.Gender))
+		}
+
+		if f.Rarity != "" && !common.AllowsRarity(f.Rarity) {
+			errs = append(errs, violation([]models.Slot{slot}, "%s %q is not allowed for rarity %s",
+				slot, common.OpenSeaTraitValue, f.Rarity))
+		}
+
+		if f.Specie != models.SpecieNone && speciesFiltered[slot] && !common.AllowsSpecie(f.Specie) {
+			errs = append(errs, violation([]models.Slot{slot}, "%s %q is not allowed for species %s",
+				slot, common.OpenSeaTraitValue, f.Specie))
+		}
+
+		for _, ref := range common.MustNotInclude {
+			excluded, err := resolveExclusion(traits, ref)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %q: %w", slot, common.OpenSeaTraitValue, err))
+				continue
+			}
+
+			if excluded.specie != models.SpecieNone && excluded.specie == f.Specie {
+				errs = append(errs, violation([]models.Slot{slot}, "%s %q must not be used by species %s",
+					slot, common.OpenSeaTraitValue, f.Specie))
+			}
+
+			for _, other := range excluded.excludes(f) {
+				errs = append(errs, violation([]models.Slot{slot, other}, "%s %q must not be combined with %s %q",
+					slot, common.OpenSeaTraitValue, other, f.Get(other).OpenSeaTraitValue))
+			}
+		}
+
+		for _, required := range common.MustInclude {
+			required = strings.TrimSpace(required)
+			if required == "" {
+				continue
+			}
+
+			others, ok := slotReference(required)
+			if !ok && !hasValue(f, required) {
+				errs = append(errs, violation([]models.Slot{slot}, "%s %q must be combined with %q",
+					slot, common.OpenSeaTraitValue, required))
+			}
+			if ok && !lo.ContainsBy(others, func(other models.Slot) bool { return f.Get(other) != nil }) {
+				errs = append(errs, violation(append([]models.Slot{slot}, others...), "%s %q must be combined with %s",
+					slot, common.OpenSeaTraitValue, required))
+			}
+		}
+
+		if back, ok := combinedBacks[slot]; ok && common.Combined.Bool() && f.Get(back) == nil {
+			errs = append(errs, violation([]models.Slot{slot}, "%s %q is combined, but has no %s",
+				slot, common.OpenSeaTraitValue, back))
+		}
+	}
+
+	if f.Hats.DataEarless != nil && f.Earrings != nil {
+		errs = append(errs, violation([]models.Slot{models.SlotHatsEarless, models.SlotEarrings},
+			"%s %q must not be combined with %s %q",
+			models.SlotHatsEarless, f.Hats.DataEarless.OpenSeaTraitValue, models.SlotEarrings, f.Earrings.OpenSeaTraitValue))
+	}
+
+	if f.Hands != nil && f.Weapons.Front == nil {
+		errs = append(errs, violation([]models.Slot{models.SlotHands, models.SlotWeaponsFront},
+			"%s %q are only used with a weapon", models.SlotHands, f.Hands.OpenSeaTraitValue))
+	}
+
+	return errs
+}
+
+// hasValue reports whether an item of the given trait value was selected.
+func hasValue(f models.FinalTraits, value string) bool {
+	return lo.ContainsBy(models.SlotList(), func(slot models.Slot) bool {
+		return f.Get(slot) != nil && f.Get(slot).OpenSeaTraitValue == value
+	})
+}
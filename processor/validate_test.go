@@ -0,0 +1,152 @@
+package processor
+
+import (
+	"errors"
+	"generator/models"
+	"reflect"
+	"testing"
+)
+
+// item returns an item of the given file name, excluding the given references.
+func item(fileName string, excluded ...string) *models.Common {
+	return &models.Common{FileName: fileName, OpenSeaTraitValue: fileName, MustNotInclude: excluded}
+}
+
+// testTraits returns traits holding the items the references of the tests name.
+func testTraits() *models.Traits {
+	return &models.Traits{
+		Bodies: &models.Commons{Data: []*models.Common{item("4B"), item("5B")}},
+		Wings:  &models.Commons{Data: []*models.Common{item("16W")}},
+		BG:     &models.Commons{Data: []*models.Common{item("6BG")}},
+	}
+}
+
+func TestValidateMustNotInclude(t *testing.T) {
+	type pick struct {
+		slot   models.Slot
+		common *models.Common
+	}
+
+	tests := []struct {
+		name     string
+		specie   models.Specie
+		picks    []pick
+		expected [][]models.Slot // Slots of every expected violation.
+	}{
+		{
+			name: "slot reference",
+			picks: []pick{
+				{models.SlotHats, item("1H", "NOSE")},
+				{models.SlotNose, item("1N")},
+			},
+			expected: [][]models.Slot{{models.SlotHats, models.SlotNose}},
+		},
+		{
+			name: "slot name",
+			picks: []pick{
+				{models.SlotHair, item("109H", "GLASSES")},
+				{models.SlotGlasses, item("1GL")},
+			},
+			expected: [][]models.Slot{{models.SlotHair, models.SlotGlasses}},
+		},
+		{
+			name: "misspelled slot reference",
+			picks: []pick{
+				{models.SlotEyes, item("170EYES", "EARLEES HAT")},
+				{models.SlotHatsEarless, item("1EH")},
+			},
+			expected: [][]models.Slot{{models.SlotEyes, models.SlotHatsEarless}},
+		},
+		{
+			name: "stackable hat group",
+			picks: []pick{
+				{models.SlotFacegears, item("20FG", "STACKABLE HATS")},
+				{models.SlotStackableHatsBack, item("1SHB")},
+			},
+			expected: [][]models.Slot{{models.SlotFacegears, models.SlotStackableHatsBack}},
+		},
+		{
+			name: "file name",
+			picks: []pick{
+				{models.SlotHatsEarless, item("1EH", "4B", "5B")},
+				{models.SlotBodies, item("4B")},
+			},
+			expected: [][]models.Slot{{models.SlotHatsEarless, models.SlotBodies}},
+		},
+		{
+			name: "file name of another item",
+			picks: []pick{
+				{models.SlotHatsEarless, item("1EH", "5B")},
+				{models.SlotBodies, item("4B")},
+			},
+		},
+		{
+			name: "file name of another case",
+			picks: []pick{
+				{models.SlotBG, item("8BG", "16w")},
+				{models.SlotWings, item("16W")},
+			},
+			expected: [][]models.Slot{{models.SlotBG, models.SlotWings}},
+		},
+		{
+			name:   "species",
+			specie: models.SpecieSoul,
+			picks: []pick{
+				{models.SlotBG, item("2BG", "SOUL")},
+			},
+			expected: [][]models.Slot{{models.SlotBG}},
+		},
+		{
+			name:   "misspelled species",
+			specie: models.SpecieOrigin,
+			picks: []pick{
+				{models.SlotBGAccent, item("8BGA", "ORGIN")},
+			},
+			expected: [][]models.Slot{{models.SlotBGAccent}},
+		},
+		{
+			name:   "other species",
+			specie: models.SpecieBeing,
+			picks: []pick{
+				{models.SlotBG, item("2BG", "SOUL", "ORGIN")},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := models.FinalTraits{Specie: test.specie}
+			for _, p := range test.picks {
+				f.Set(p.slot, p.common)
+			}
+
+			var slots [][]models.Slot
+			for _, err := range Validate(testTraits(), f) {
+				var v *Violation
+				if !errors.As(err, &v) {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				slots = append(slots, v.Slots)
+			}
+
+			if !reflect.DeepEqual(slots, test.expected) {
+				t.Errorf("violations of slots %v, expected %v", slots, test.expected)
+			}
+		})
+	}
+}
+
+func TestValidateUnknownReference(t *testing.T) {
+	f := models.FinalTraits{}
+	f.Set(models.SlotHats, item("1H", "99B"))
+
+	errs := Validate(testTraits(), f)
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, expected 1", len(errs))
+	}
+
+	var v *Violation
+	if errors.As(errs[0], &v) {
+		t.Errorf("unknown reference reported as a violation: %s", errs[0])
+	}
+}
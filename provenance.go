@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"generator/provenance"
+	"log"
+)
+
+// executeProvenance computes the provenance hash of the first nrNFTs
+// generated tokens and writes it, with the provenance table, to output. When
+// a block hash is given, the starting-index offset derived from it is applied
+// to the table.
+func executeProvenance(nrNFTs int, blockHash, output string) {
+	record, err := provenance.Compute(nrNFTs,
+		func(tokenID int) string { return fmt.Sprintf("./assets/results/images/%d.png", tokenID) },
+		func(tokenID int) string { return fmt.Sprintf("./assets/results/metadata/%d.json", tokenID) },
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if blockHash != "" {
+		if err := record.ApplyStartingIndex(blockHash); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Starting index: %d", *record.StartingIndex)
+	}
+
+	writeToSimpleFile(output, record)
+
+	log.Printf("Provenance hash: %s", record.Hash)
+}
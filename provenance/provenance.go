@@ -0,0 +1,117 @@
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// Token is a row of the provenance table.
+type Token struct {
+	TokenID         int    `json:"token_id"`                    // Token ID in generation order.
+	ImageHash       string `json:"image_hash"`                  // SHA-256 of the image file.
+	MetadataHash    string `json:"metadata_hash"`               // SHA-256 of the metadata file.
+	RevealedTokenID *int   `json:"revealed_token_id,omitempty"` // Token ID after the starting-index offset, if any.
+}
+
+// Record holds the provenance of a collection: the hash to publish before the
+// reveal and the table proving it.
+type Record struct {
+	Hash          string  `json:"provenance_hash"`          // SHA-256 of the concatenated image hashes.
+	BlockHash     string  `json:"block_hash,omitempty"`     // Block hash the starting index was derived from.
+	StartingIndex *int    `json:"starting_index,omitempty"` // Offset applied to the token IDs at reveal.
+	Tokens        []Token `json:"tokens"`                   // Provenance table, in token order.
+}
+
+// Compute hashes the images and metadata of the first count tokens. The
+// provenance hash is the SHA-256 of the hex image hashes concatenated in token
+// order. imagePath and metadataPath return the file of a token.
+func Compute(count int, imagePath, metadataPath func(tokenID int) string) (*Record, error) {
+	record := &Record{
+		Tokens: make([]Token, 0, count),
+	}
+
+	var concatenated strings.Builder
+	for tokenID := 0; tokenID < count; tokenID++ {
+		imageHash, err := HashFile(imagePath(tokenID))
+		if err != nil {
+			return nil, fmt.Errorf("hashing image of token %d: %w", tokenID, err)
+		}
+
+		metadataHash, err := HashFile(metadataPath(tokenID))
+		if err != nil {
+			return nil, fmt.Errorf("hashing metadata of token %d: %w", tokenID, err)
+		}
+
+		concatenated.WriteString(imageHash)
+		record.Tokens = append(record.Tokens, Token{
+			TokenID:      tokenID,
+			ImageHash:    imageHash,
+			MetadataHash: metadataHash,
+		})
+	}
+
+	sum := sha256.Sum256([]byte(concatenated.String()))
+	record.Hash = hex.EncodeToString(sum[:])
+
+	return record, nil
+}
+
+// ApplyStartingIndex derives the starting index from a block hash and records
+// the revealed token ID of every token, (tokenID + startingIndex) % count.
+func (r *Record) ApplyStartingIndex(blockHash string) error {
+	startingIndex, err := StartingIndex(blockHash, len(r.Tokens))
+	if err != nil {
+		return err
+	}
+
+	r.BlockHash = blockHash
+	r.StartingIndex = &startingIndex
+
+	for i := range r.Tokens {
+		revealed := (r.Tokens[i].TokenID + startingIndex) % len(r.Tokens)
+		r.Tokens[i].RevealedTokenID = &revealed
+	}
+
+	return nil
+}
+
+// StartingIndex returns uint256(blockHash) % count, the starting index a
+// contract derives from the hash of the block it was revealed at.
+func StartingIndex(blockHash string, count int) (int, error) {
+	if count <= 0 {
+		return 0, fmt.Errorf("no tokens to offset")
+	}
+
+	digits := strings.TrimPrefix(strings.ToLower(blockHash), "0x")
+	if strings.Trim(digits, "0123456789abcdef") != "" {
+		return 0, fmt.Errorf("invalid block hash: %s", blockHash)
+	}
+
+	value, ok := new(big.Int).SetString(digits, 16)
+	if !ok {
+		return 0, fmt.Errorf("invalid block hash: %s", blockHash)
+	}
+
+	return int(value.Mod(value, big.NewInt(int64(count))).Int64()), nil
+}
+
+// HashFile returns the hex SHA-256 of a file.
+func HashFile(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,105 @@
+package provenance
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// genesisHash is the hash of the Ethereum genesis block.
+const genesisHash = "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"
+
+func TestStartingIndex(t *testing.T) {
+	tests := []struct {
+		name      string
+		blockHash string
+		count     int
+		expected  int
+		fails     bool
+	}{
+		{name: "without prefix", blockHash: genesisHash, count: 7573, expected: 2162},
+		{name: "with prefix", blockHash: "0x" + genesisHash, count: 7573, expected: 2162},
+		{name: "upper case", blockHash: "0XD4E56740F876AEF8C010B86A40D5F56745A118D0906A34E69AEC8C0DB1CB8FA3", count: 7573, expected: 2162},
+		{name: "other count", blockHash: "0x" + genesisHash, count: 10000, expected: 4627},
+		{name: "single token", blockHash: genesisHash, count: 1, expected: 0},
+		{name: "small hash", blockHash: "0x0a", count: 7573, expected: 10},
+		{name: "invalid hex", blockHash: "0xd4e5zz", count: 7573, fails: true},
+		{name: "empty hash", blockHash: "", count: 7573, fails: true},
+		{name: "signed hash", blockHash: "-1", count: 7573, fails: true},
+		{name: "no tokens", blockHash: genesisHash, count: 0, fails: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := StartingIndex(test.blockHash, test.count)
+			if test.fails {
+				if err == nil {
+					t.Errorf("StartingIndex(%q, %d) = %d, expected an error", test.blockHash, test.count, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.expected {
+				t.Errorf("StartingIndex(%q, %d) = %d, expected %d", test.blockHash, test.count, got, test.expected)
+			}
+		})
+	}
+}
+
+func TestCompute(t *testing.T) {
+	dir := t.TempDir()
+	for tokenID, content := range []string{"a", "b"} {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.png", tokenID)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.json", tokenID)), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	record, err := Compute(2,
+		func(tokenID int) string { return filepath.Join(dir, fmt.Sprintf("%d.png", tokenID)) },
+		func(tokenID int) string { return filepath.Join(dir, fmt.Sprintf("%d.json", tokenID)) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sha256("a") followed by sha256("b"), hashed again.
+	if expected := "62af5c3cb8da3e4f25061e829ebeea5c7513c54949115b1acc225930a90154da"; record.Hash != expected {
+		t.Errorf("provenance hash = %s, expected %s", record.Hash, expected)
+	}
+
+	expected := []Token{
+		{TokenID: 0, ImageHash: "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"},
+		{TokenID: 1, ImageHash: "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"},
+	}
+	for i, token := range record.Tokens {
+		if token.TokenID != expected[i].TokenID || token.ImageHash != expected[i].ImageHash {
+			t.Errorf("token %d = %+v, expected %+v", i, token, expected[i])
+		}
+		if token.MetadataHash != "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a" {
+			t.Errorf("token %d: metadata hash = %s", i, token.MetadataHash)
+		}
+	}
+
+	if err := record.ApplyStartingIndex("0x01"); err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range record.Tokens {
+		if revealed := (token.TokenID + 1) % 2; token.RevealedTokenID == nil || *token.RevealedTokenID != revealed {
+			t.Errorf("token %d revealed as %v, expected %d", token.TokenID, token.RevealedTokenID, revealed)
+		}
+	}
+}
+
+func TestComputeMissingFile(t *testing.T) {
+	_, err := Compute(1,
+		func(int) string { return filepath.Join(t.TempDir(), "0.png") },
+		func(int) string { return filepath.Join(t.TempDir(), "0.json") })
+	if err == nil {
+		t.Error("Compute did not fail on a missing image")
+	}
+}
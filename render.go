@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"generator/models"
+	"generator/parse"
+	"generator/processor"
+	"io/ioutil"
+	"log"
+)
+
+// executeRender renders the tokens listed in a plan file instead of picking
+// their traits. The file is either the output of a dry run or a hand-curated
+// list of tokens, where each layer names its slot and either the file name or
+// the OpenSea value of the trait. Tokens breaking the compatibility rules are
+// skipped unless force is set.
+func executeRender(planName string, force bool) {
+	specs, err := readPlan(planName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tr := parse.Do()
+
+	resolved := make(chan TokenSpec, queueSize)
+	go func() {
+		defer close(resolved)
+
+		for _, spec := range specs {
+			if spec.TokenID < 0 || spec.TokenID >= len(responses) {
+				log.Printf("Skipping unknown token %d", spec.TokenID)
+				continue
+			}
+
+			if len(spec.Layers) == 0 && responses[spec.TokenID].GetRarity().IsOneOfOne() {
+				resolved <- oneOfOneSpec(spec.TokenID)
+				continue
+			}
+
+			spec, errs := resolveSpec(tr, spec)
+			if len(errs) > 0 {
+				for _, err := range errs {
+					log.Printf("Token %d: %s", spec.TokenID, err)
+				}
+				if !force {
+					log.Printf("Skipping token %d", spec.TokenID)
+					continue
+				}
+			}
+			resolved <- spec
+		}
+	}()
+
+	results := openResults()
+	runPipeline(resolved, results)
+	closeResults(results)
+}
+
+// readPlan loads the token specs of a plan file.
+func readPlan(name string) ([]TokenSpec, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan: %w", err)
+	}
+
+	var specs []TokenSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("parsing plan %s: %w", name, err)
+	}
+
+	return specs, nil
+}
+
+// resolveSpec looks up every layer of a token spec in the parsed traits,
+// orders the layers for compositing and rebuilds the metadata the same way
+// processToken does. It returns the unknown layers and the broken
+// compatibility rules as errors.
+func resolveSpec(traits *models.Traits, spec TokenSpec) (TokenSpec, []error) {
+	var errs []error
+
+	metadata := responses[spec.TokenID].Copy()
+	if spec.Metadata != nil && spec.Metadata.Seed != "" {
+		metadata.Seed = spec.Metadata.Seed
+	}
+
+	final := models.FinalTraits{
+		Metadata: metadata,
+		Rarity:   metadata.GetRarity(),
+		Specie:   metadata.GetSpecie(),
+		Category: spec.Category,
+		Gender:   spec.Gender,
+	}
+	if spec.HasHair != nil {
+		final.HasHair = *spec.HasHair
+	}
+
+	for _, layer := range spec.Layers {
+		if layer.Slot.IsInvalid() {
+			errs = append(errs, fmt.Errorf("unknown slot %q", layer.Slot))
+			continue
+		}
+
+		candidates := traits.Candidates(layer.Slot)
+
+		common := processor.ExtractByFileName(candidates, layer.FileName)
+		if common == nil && layer.FileName == "" {
+			common = processor.ExtractByTraitValue(candidates, layer.Name)
+		}
+		if common == nil {
+			errs = append(errs, fmt.Errorf("no %s trait with file %q or value %q", layer.Slot, layer.FileName, layer.Name))
+			continue
+		}
+
+		final.Set(layer.Slot, common)
+	}
+
+	errs = append(errs, processor.Validate(traits, final)...)
+
+	var layers []TraitData
+	for _, slot := range models.SlotList() {
+		common := final.Get(slot)
+		if common == nil {
+			continue
+		}
+
+		if attribute, ok := slotAttribute(slot, common); ok {
+			metadata.Attributes = append(metadata.Attributes, attribute)
+		}
+
+		layers = append(layers, TraitData{
+			Slot:     slot,
+			Folder:   slot.Folder(),
+			Name:     common.OpenSeaTraitValue,
+			FileName: common.FileName,
+		})
+	}
+
+	metadata.Attributes = append(metadata.Attributes, derivedAttributes(final)...)
+
+	resolved := newTokenSpec(spec.TokenID, final, layers, metadata)
+	if spec.HasHair == nil {
+		resolved.HasHair = nil
+	}
+
+	return resolved, errs
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"generator/collector"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// URIForm selects how IPFS URIs are written.
+type URIForm string
+
+// Constants representing the IPFS URI forms.
+const (
+	URIFormKeep    URIForm = ""        // Keep URIs as the templates produce them
+	URIFormNative  URIForm = "native"  // ipfs://{cid}/{path}
+	URIFormGateway URIForm = "gateway" // {gateway}/ipfs/{cid}/{path}
+)
+
+// RewriteOptions describes how the metadata URIs are rewritten. A template
+// left empty keeps the URI. In templates, "{id}" is replaced by the token ID
+// and every other "{key}" by Params[key], such as "ipfs://{cid}/{id}.png".
+// A placeholder without a value, or with an empty one, is an error.
+type RewriteOptions struct {
+	Image        string            // Template of the image URI.
+	Animation    string            // Template of the animation URI.
+	External     string            // Template of the external URL.
+	Params       map[string]string // Values of the template placeholders other than "{id}".
+	Form         URIForm           // Form of the IPFS URIs.
+	Gateway      string            // Gateway used by URIFormGateway, such as "https://ipfs.io".
+	AllowMissing bool              // Rewrite tokens whose files are missing locally instead of skipping them.
+}
+
+var (
+	gatewayURI  = regexp.MustCompile(`^https?://[^/]+/ipfs/(.+)$`) // IPFS URI in the gateway form, capturing its content path
+	placeholder = regexp.MustCompile(`\{[^{}]*\}`)                 // Template placeholder such as "{cid}"
+)
+
+// executeRewriteURIs rewrites the image, animation and external URIs of the
+// metadata of the first nrNFTs tokens. The image and animation each file
+// points to are checked to exist locally before the metadata is rewritten.
+func executeRewriteURIs(nrNFTs int, opts RewriteOptions) {
+	switch opts.Form {
+	case URIFormKeep, URIFormNative, URIFormGateway:
+	default:
+		log.Fatalf("invalid URI form: %s", opts.Form)
+	}
+
+	// Check the templates once, before any file is rewritten.
+	for _, template := range []string{opts.Image, opts.Animation, opts.External} {
+		if _, err := opts.expand(template, 0); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var missing int
+
+	for tokenID := 0; tokenID < nrNFTs; tokenID++ {
+		metadata, err := collector.GetMetadataWithError(strconv.Itoa(tokenID))
+		if err != nil {
+			log.Printf("File not found: %d: %s", tokenID, err)
+			continue
+		}
+
+		image, err := opts.expand(opts.Image, tokenID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		animation, err := opts.expand(opts.Animation, tokenID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		external, err := opts.expand(opts.External, tokenID)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := checkLocalFile("./assets/results/images/", image); err != nil {
+			log.Printf("Token %d: %s", tokenID, err)
+			missing++
+			if !opts.AllowMissing {
+				continue
+			}
+		}
+		if err := checkLocalFile("./assets/results/animations/", animation); err != nil {
+			log.Printf("Token %d: %s", tokenID, err)
+			missing++
+			if !opts.AllowMissing {
+				continue
+			}
+		}
+
+		if image != "" {
+			metadata.Image = image
+		}
+		if animation != "" {
+			metadata.AnimationURL = animation
+		}
+		if external != "" {
+			metadata.ExternalURL = external
+		}
+
+		metadata.Image = opts.convert(metadata.Image)
+		metadata.AnimationURL = opts.convert(metadata.AnimationURL)
+		metadata.ExternalURL = opts.convert(metadata.ExternalURL)
+
+		writeToSimpleFile(fmt.Sprintf("./assets/results/metadata/%d.json", tokenID), metadata)
+	}
+
+	if missing > 0 {
+		log.Printf("%d referenced files are missing locally", missing)
+	}
+}
+
+// expand fills a URI template for a token. Returns an empty string for an
+// empty template, and an error when a placeholder has no value or an empty one.
+func (o RewriteOptions) expand(template string, tokenID int) (string, error) {
+	if template == "" {
+		return "", nil
+	}
+
+	var err error
+	result := placeholder.ReplaceAllStringFunc(template, func(p string) string {
+		key := strings.Trim(p, "{}")
+		if key == "id" {
+			return strconv.Itoa(tokenID)
+		}
+
+		value := o.Params[key]
+		if value == "" && err == nil {
+			err = fmt.Errorf("no value for %s in the template %q", p, template)
+		}
+		return value
+	})
+
+	return result, err
+}
+
+// convert writes an IPFS URI in the configured form. Other URIs are returned as is.
+func (o RewriteOptions) convert(uri string) string {
+	switch o.Form {
+	case URIFormNative:
+		if match := gatewayURI.FindStringSubmatch(uri); match != nil {
+			return "ipfs://" + match[1]
+		}
+	case URIFormGateway:
+		if strings.HasPrefix(uri, "ipfs://") {
+			return strings.TrimSuffix(o.Gateway, "/") + "/ipfs/" + strings.TrimPrefix(uri, "ipfs://")
+		}
+	}
+	return uri
+}
+
+// checkLocalFile checks that the file a URI points to exists in folder,
+// looking it up by the last element of the URI. An empty URI is not checked.
+func checkLocalFile(folder, uri string) error {
+	if uri == "" {
+		return nil
+	}
+
+	name := folder + path.Base(uri)
+	if _, err := os.Stat(name); err != nil {
+		return fmt.Errorf("%s points to a missing file: %s", uri, name)
+	}
+
+	return nil
+}
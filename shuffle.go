@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"generator/collector"
+	"generator/config"
+	"generator/models"
+	"generator/provenance"
+	"generator/utils"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// shuffleMappingFile keeps the private mapping of a shuffle, outside the published results.
+const shuffleMappingFile = "./out/shuffle_mapping.json"
+
+// ShuffleMapping records how a shuffle moved the tokens, so it can be audited later.
+type ShuffleMapping struct {
+	Method string         `json:"method"`           // "seed" or "offset".
+	Seed   string         `json:"seed,omitempty"`   // Seed of the Fisher–Yates shuffle.
+	Source string         `json:"source,omitempty"` // Random source the seed drove.
+	Offset int            `json:"offset"`           // Offset added to every token ID.
+	Tokens []ShuffleToken `json:"tokens"`           // Where every token was moved, in original order.
+}
+
+// ShuffleToken maps a token from its generation ID to its published ID.
+type ShuffleToken struct {
+	Original int `json:"original"`
+	Shuffled int `json:"shuffled"`
+}
+
+var (
+	nameNumber = regexp.MustCompile(`#\d+`)               // "Seizon #12"
+	uriNumber  = regexp.MustCompile(`([/=])\d+(\.\w+)?$`) // ".../12.png", ".../token/12" or "...?id=12"
+)
+
+// executeShuffle permutes the images and metadata of the first nrNFTs tokens,
+// so the published token IDs do not reveal the generation order. With a seed
+// the permutation is a Fisher–Yates shuffle; otherwise every token is moved by
+// offset, or by the starting index derived from blockHash when it is given.
+//
+// A collection is shuffled once: the command refuses to run when a mapping
+// already exists, as shuffling again would replace the only record of the
+// first permutation.
+func executeShuffle(nrNFTs int, seed string, offset int, blockHash string) {
+	if _, err := os.Stat(shuffleMappingFile); err == nil {
+		log.Fatalf("%s already exists: the results were shuffled already, nothing was moved", shuffleMappingFile)
+	} else if !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+
+	mapping := ShuffleMapping{
+		Method: "offset",
+		Offset: offset,
+	}
+
+	var permutation []int
+	if seed != "" {
+		kind := config.Get().RandomSource
+		permutation = utils.NewRandomizerWith(kind, seed, 0).Permutation(nrNFTs)
+		mapping.Method = "seed"
+		mapping.Seed = seed
+		mapping.Source = kind.String()
+	} else {
+		if blockHash != "" {
+			startingIndex, err := provenance.StartingIndex(blockHash, nrNFTs)
+			if err != nil {
+				log.Fatal(err)
+			}
+			mapping.Offset = startingIndex
+		}
+
+		permutation = make([]int, nrNFTs)
+		for tokenID := range permutation {
+			permutation[tokenID] = ((tokenID+mapping.Offset)%nrNFTs + nrNFTs) % nrNFTs
+		}
+	}
+
+	// Read every metadata file and check every image first, so nothing is moved if one is missing.
+	metadata := make([]*models.APIResponse, nrNFTs)
+	for tokenID := range metadata {
+		m, err := collector.GetMetadataWithError(strconv.Itoa(tokenID))
+		if err != nil {
+			log.Fatalf("File not found: %d: %s", tokenID, err)
+		}
+		metadata[tokenID] = m
+
+		if _, err := os.Stat(fmt.Sprintf("./assets/results/images/%d.png", tokenID)); err != nil {
+			log.Fatalf("Image not found: %d: %s", tokenID, err)
+		}
+	}
+
+	for tokenID, shuffled := range permutation {
+		mapping.Tokens = append(mapping.Tokens, ShuffleToken{
+			Original: tokenID,
+			Shuffled: shuffled,
+		})
+	}
+
+	// The mapping is the only record of the permutation: it is safely on disk
+	// before any result file is touched.
+	body, err := json.MarshalIndent(mapping, "", "\t")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := writeFileSync(shuffleMappingFile, body); err != nil {
+		log.Fatalf("Failed to write the shuffle mapping, nothing was moved: %s", err)
+	}
+
+	// Move the images in two steps, as the target of a move may not have moved yet.
+	for tokenID := range permutation {
+		name := fmt.Sprintf("./assets/results/images/%d.png", tokenID)
+		if err := os.Rename(name, name+".shuffle"); err != nil {
+			log.Fatalf("Failed to move image of token %d: %s", tokenID, err)
+		}
+	}
+	for tokenID, shuffled := range permutation {
+		from := fmt.Sprintf("./assets/results/images/%d.png.shuffle", tokenID)
+		to := fmt.Sprintf("./assets/results/images/%d.png", shuffled)
+		if err := os.Rename(from, to); err != nil {
+			log.Fatalf("Failed to move image of token %d: %s", tokenID, err)
+		}
+	}
+
+	for tokenID, shuffled := range permutation {
+		m := metadata[tokenID]
+		m.TokenID = shuffled
+		m.Name = nameNumber.ReplaceAllString(m.Name, "#"+strconv.Itoa(shuffled))
+		m.Image = renumberURI(m.Image, shuffled)
+		m.AnimationURL = renumberURI(m.AnimationURL, shuffled)
+		m.ExternalURL = renumberURI(m.ExternalURL, shuffled)
+		writeToSimpleFile(fmt.Sprintf("./assets/results/metadata/%d.json", shuffled), m)
+	}
+
+	log.Printf("Shuffled %d tokens, mapping written to %s", nrNFTs, shuffleMappingFile)
+}
+
+// writeFileSync writes a private file through a temporary file that is
+// synced and renamed, so the file is either complete and on disk or absent.
+func writeFileSync(name string, body []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return err
+	}
+
+	// Sync the folder too, so the rename itself survives a crash.
+	dir, err := os.Open(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// renumberURI points a URI ending in "/{tokenID}.{ext}", "/{tokenID}" or
+// "={tokenID}" to the shuffled token ID.
+func renumberURI(uri string, tokenID int) string {
+	return uriNumber.ReplaceAllString(uri, "${1}"+strconv.Itoa(tokenID)+"$2")
+}
@@ -0,0 +1,39 @@
+package main
+
+import "log"
+
+// StorageBackend packs the results for a decentralized storage network.
+// Publishing is done in two steps, since the metadata must point to the
+// images: the images are packed first, then the metadata is rewritten with
+// the values the images are published under, then the metadata is packed.
+type StorageBackend interface {
+	// PackImages prepares the images for upload. It returns the values of the
+	// URI template placeholders, or nil when they are not known yet.
+	PackImages() (map[string]string, error)
+
+	// PackMetadata prepares the rewritten metadata for upload.
+	PackMetadata() error
+}
+
+// publish packs the results with the backend, rewriting the metadata of the
+// first nrNFTs tokens in between. The rewrite is skipped without an image
+// template or when the backend could not tell where the images are.
+func publish(backend StorageBackend, nrNFTs int, rewrite RewriteOptions) {
+	params, err := backend.PackImages()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch {
+	case rewrite.Image == "":
+	case params == nil:
+		log.Println("The image location is not known yet, the metadata URIs were not rewritten")
+	default:
+		rewrite.Params = params
+		executeRewriteURIs(nrNFTs, rewrite)
+	}
+
+	if err := backend.PackMetadata(); err != nil {
+		log.Fatal(err)
+	}
+}
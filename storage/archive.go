@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Archive streams the results into a zip or gzipped tar archive. Each file is
+// buffered until its writer is closed, then appended to the archive, so files
+// can be written concurrently. The archive is written to a temporary file and
+// only moved to its path once it is complete.
+type Archive struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	zip  *zip.Writer
+	gz   *gzip.Writer
+	tar  *tar.Writer
+}
+
+// NewArchive creates an archive of the given kind at path.
+func NewArchive(path string, kind Kind) (*Archive, error) {
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Archive{
+		path: path,
+		file: file,
+	}
+
+	switch kind {
+	case KindZip:
+		a.zip = zip.NewWriter(file)
+	case KindTarGz:
+		a.gz = gzip.NewWriter(file)
+		a.tar = tar.NewWriter(a.gz)
+	default:
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("invalid archive type: %s", kind)
+	}
+
+	return a, nil
+}
+
+// Create returns a writer adding the named file to the archive once closed.
+func (a *Archive) Create(name string) (io.WriteCloser, error) {
+	return &bufferedFile{
+		flush: func(data []byte) error { return a.add(name, data) },
+	}, nil
+}
+
+// add appends a file to the archive.
+func (a *Archive) add(name string, data []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+
+	if a.zip != nil {
+		w, err := a.zip.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: now,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	err := a.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  now,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = a.tar.Write(data)
+	return err
+}
+
+// Close writes the end of the archive and moves it to its path.
+func (a *Archive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var err error
+	if a.zip != nil {
+		err = a.zip.Close()
+	} else {
+		err = a.tar.Close()
+		if gzErr := a.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(a.file.Name())
+		return err
+	}
+
+	return os.Rename(a.file.Name(), a.path)
+}
+
+// bufferedFile holds the content of a file until it is closed.
+type bufferedFile struct {
+	bytes.Buffer
+	flush  func(data []byte) error
+	closed bool
+}
+
+// Close stores the buffered content.
+func (f *bufferedFile) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+
+	return f.flush(f.Bytes())
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Local writes the results to a local folder, creating sub-folders as needed.
+// Each file is written to a temporary file first and renamed into place once
+// closed, so readers never see a partly written file.
+type Local struct {
+	Root string // Folder the names are relative to.
+}
+
+// NewLocal returns a storage writing under root.
+func NewLocal(root string) *Local {
+	return &Local{Root: root}
+}
+
+// Create creates the named file under the root.
+func (l *Local) Create(name string) (io.WriteCloser, error) {
+	path := filepath.Join(l.Root, filepath.FromSlash(name))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &localFile{File: file, path: path}, nil
+}
+
+// Close does nothing, as every file is complete once closed.
+func (l *Local) Close() error {
+	return nil
+}
+
+// localFile is a temporary file renamed to its path once closed.
+type localFile struct {
+	*os.File
+	path   string
+	closed bool
+}
+
+// Close closes the temporary file and moves it to its path.
+func (f *localFile) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	// Temporary files are created private; results are meant to be shared.
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), f.path)
+}
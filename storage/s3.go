@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config describes an S3-compatible bucket, such as AWS S3 or MinIO.
+// Credentials left empty are read from the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+type S3Config struct {
+	Endpoint     string `json:"endpoint"`      // Base URL of the service, such as "https://s3.amazonaws.com" or "http://localhost:9000".
+	Region       string `json:"region"`        // Region the requests are signed for; us-east-1 by default.
+	Bucket       string `json:"bucket"`        // Bucket the objects are written to.
+	Prefix       string `json:"prefix"`        // Prefix of every object key, such as "collection/".
+	PathStyle    bool   `json:"path_style"`    // Address the bucket in the path instead of the host, as MinIO expects.
+	AccessKey    string `json:"access_key"`    // Access key ID.
+	SecretKey    string `json:"secret_key"`    // Secret access key.
+	SessionToken string `json:"session_token"` // Session token of temporary credentials.
+}
+
+// S3 uploads the results to an S3-compatible bucket. Requests are signed
+// with AWS Signature Version 4. Each file is buffered until its writer is
+// closed, then uploaded with a single PUT.
+type S3 struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3 returns a storage uploading to the configured bucket.
+func NewS3(c S3Config) (*S3, error) {
+	if c.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is missing")
+	}
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = "https://s3." + c.Region + ".amazonaws.com"
+	}
+	if c.AccessKey == "" {
+		c.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if c.SecretKey == "" {
+		c.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if c.SessionToken == "" {
+		c.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return nil, fmt.Errorf("S3 credentials are missing")
+	}
+
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint: %s", c.Endpoint)
+	}
+
+	return &S3{
+		config:   c,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+		now:      time.Now,
+	}, nil
+}
+
+// Create returns a writer uploading the named object once closed.
+func (s *S3) Create(name string) (io.WriteCloser, error) {
+	return &bufferedFile{
+		flush: func(data []byte) error { return s.put(name, data) },
+	}, nil
+}
+
+// Close does nothing, as every object is uploaded once its writer is closed.
+func (s *S3) Close() error {
+	return nil
+}
+
+// put uploads an object.
+func (s *S3) put(name string, data []byte) error {
+	u := *s.endpoint
+	key := s.config.Prefix + name
+	if s.config.PathStyle {
+		u.Path = path.Join("/", u.Path, s.config.Bucket, key)
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = path.Join("/", u.Path, key)
+	}
+	u.RawPath = escapePath(u.Path) // Sent exactly as signed.
+
+	req, err := http.NewRequest(http.MethodPut, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if s.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+
+	digest := sha256.Sum256(data)
+	s.sign(req, hex.EncodeToString(digest[:]), s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("uploading %s: %s: %s", key, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// sign adds the Signature Version 4 headers to a request. Every header already
+// set on the request is signed, together with the host.
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", amzDate[:8], s.config.Region)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretKey), amzDate[:8])
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapePath URI-encodes every byte of a path but the unreserved characters and the slashes.
+func escapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery encodes the query parameters sorted by name.
+func canonicalQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
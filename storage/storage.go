@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Kind selects where the results are written.
+type Kind string
+
+// Constants representing the storage kinds.
+const (
+	KindLocal Kind = "local"  // Files in a local folder
+	KindZip   Kind = "zip"    // Entries of a zip archive
+	KindTarGz Kind = "tar.gz" // Entries of a gzipped tar archive
+	KindS3    Kind = "s3"     // Objects of an S3-compatible bucket
+)
+
+// IsValid checks if the kind is one of the defined kinds.
+func (k Kind) IsValid() bool {
+	switch k {
+	case KindLocal, KindZip, KindTarGz, KindS3:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsInvalid checks if the kind is invalid by negating IsValid.
+func (k Kind) IsInvalid() bool {
+	return !k.IsValid()
+}
+
+// String returns the string representation of the Kind.
+func (k Kind) String() string {
+	return string(k)
+}
+
+// Storage receives the results of a run. Names are slash-separated paths
+// relative to the results root, such as "images/0.png". Files may be created
+// concurrently; a file is only stored once its writer is closed.
+type Storage interface {
+	// Create returns a writer storing the named file, replacing any previous one.
+	Create(name string) (io.WriteCloser, error)
+
+	// Close finishes the storage, such as the trailer of an archive. Files
+	// must not be created afterwards.
+	Close() error
+}
+
+// Config describes the storage the results are written to.
+type Config struct {
+	Type Kind     `json:"type"` // Storage kind; local by default.
+	Path string   `json:"path"` // Folder of a local storage, or file of an archive.
+	S3   S3Config `json:"s3"`   // Bucket of an S3 storage.
+}
+
+// Open opens the storage described by the configuration.
+func Open(c Config) (Storage, error) {
+	switch c.Type {
+	case KindLocal:
+		return NewLocal(c.Path), nil
+	case KindZip, KindTarGz:
+		return NewArchive(c.Path, c.Type)
+	case KindS3:
+		return NewS3(c.S3)
+	default:
+		return nil, fmt.Errorf("invalid storage type: %s", c.Type)
+	}
+}
+
+// WriteJSON stores data as indented JSON in the named file.
+func WriteJSON(s Storage, name string, data interface{}) error {
+	body, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	w, err := s.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
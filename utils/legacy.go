@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"generator/models"
+	"math/big"
+)
+
+// legacyRetries is the number of draws the original sampler made before
+// giving up on picking an item.
+const legacyRetries = 10
+
+// legacy reports whether the randomizer draws as the original sampler did.
+func (r *Randomizer) legacy() bool {
+	return r.Kind == SourceSHA1Legacy
+}
+
+// legacyNumber returns sha1(seed + counter) modulo max+1, as the original
+// sampler did, modulo bias included.
+func (r *Randomizer) legacyNumber(max int) int {
+	return int(r.Uint64() % uint64(max+1))
+}
+
+// legacyCategory draws a number in [0, 100] and returns the first entry whose
+// cumulative weight, scaled to 100, is at least that number.
+func (r *Randomizer) legacyCategory(weights []*big.Rat) int {
+	randomNumber := float64(r.legacyNumber(100))
+
+	total := legacyTotal(weights)
+	last := -1
+	cumulative := 0.0
+	for i, w := range weights {
+		f, _ := w.Float64()
+		if f <= 0 {
+			continue
+		}
+		cumulative += f / total * 100
+		last = i
+		if randomNumber <= cumulative {
+			return i
+		}
+	}
+
+	return last
+}
+
+// legacyGender draws a number in [0, 100] and picks the first entry, the
+// male gender, when it is below its weight scaled to 100.
+func (r *Randomizer) legacyGender(weights []*big.Rat) int {
+	randomNumber := float64(r.legacyNumber(100))
+
+	first, _ := weights[0].Float64()
+	if randomNumber < first/legacyTotal(weights)*100 {
+		return 0
+	}
+	return 1
+}
+
+// legacyChance draws a number in [0, scale] and reports whether it is below
+// the percentage scaled to scale/100.
+func (r *Randomizer) legacyChance(percentage float64, scale int) bool {
+	return r.legacyNumber(scale) < int(percentage*float64(scale/100))
+}
+
+// legacyRandom picks an item as the original sampler did: a number in
+// [0, 100000] is drawn against the cumulative normalized distributions, up to
+// legacyRetries times. Returns -1 if nothing is picked.
+func (r *Randomizer) legacyRandom(data []*models.Common) int {
+	var percentages []float64
+
+	var lastPercentage float64
+	for _, percent := range NormalizeDistribution(data) {
+		lastPercentage += percent * 1000
+		percentages = append(percentages, lastPercentage)
+	}
+
+	for i := 0; i < legacyRetries; i++ {
+		randomNumber := r.legacyNumber(100 * 1000)
+
+		for j, v := range percentages {
+			if randomNumber <= int(v) {
+				return j
+			}
+		}
+
+		if IsRandomizerDone() {
+			break
+		}
+	}
+
+	return -1
+}
+
+// legacyTotal returns the sum of the weights, as a float.
+func legacyTotal(weights []*big.Rat) float64 {
+	total := 0.0
+	for _, w := range weights {
+		if f, _ := w.Float64(); f > 0 {
+			total += f
+		}
+	}
+	return total
+}
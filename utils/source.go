@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strconv"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Source is a deterministic stream of random 64-bit values, derived from a seed.
+type Source interface {
+	Uint64() uint64
+}
+
+// SourceKind identifies a Source implementation. It is recorded with every
+// generated token so its run can be replayed with the same stream.
+// SourceSHA1Legacy also draws as the original sampler did, with modulo bias
+// and float percentages, so the tokens of runs that recorded no source are
+// replayed with their original draws.
+type SourceKind string
+
+// Constants representing the available sources.
+const (
+	SourceSHA1       SourceKind = "sha1"        // sha1(seed + counter) (default)
+	SourceSHA1Legacy SourceKind = "sha1-legacy" // sha1(seed + counter), drawn by the original sampler
+	SourcePCG        SourceKind = "pcg"         // PCG-DXSM seeded with sha256(seed), much faster per draw
+	SourceKeccak     SourceKind = "keccak"      // keccak256(seed, tokenID, counter), reproducible on-chain
+)
+
+// IsValid checks if the source kind is one of the available sources.
+// An empty kind is valid and means SourceSHA1.
+func (k SourceKind) IsValid() bool {
+	switch k {
+	case "", SourceSHA1, SourceSHA1Legacy, SourcePCG, SourceKeccak:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsInvalid checks if the source kind is invalid by negating IsValid.
+func (k SourceKind) IsInvalid() bool {
+	return !k.IsValid()
+}
+
+// String returns the string representation of the SourceKind.
+func (k SourceKind) String() string {
+	return string(k)
+}
+
+// NewSource creates the source of the given kind. The token ID is only used
+// by SourceKeccak, which mixes it into every draw.
+func NewSource(kind SourceKind, seed string, tokenID int) Source {
+	switch kind {
+	case "", SourceSHA1, SourceSHA1Legacy:
+		return &sha1Source{seed: seed}
+	case SourcePCG:
+		return newPCGSource(seed)
+	case SourceKeccak:
+		return &keccakSource{seed: seed, tokenID: uint64(tokenID)}
+	default:
+		panic(fmt.Errorf("invalid source: %s", kind))
+	}
+}
+
+// sha1Source hashes the seed followed by the decimal counter, one SHA1 per draw.
+type sha1Source struct {
+	seed    string
+	counter int
+}
+
+// Uint64 returns the first 8 bytes of sha1(seed + counter) and increments the counter.
+func (s *sha1Source) Uint64() uint64 {
+	mu.Lock()
+	var data = s.seed + strconv.Itoa(s.counter)
+	s.counter++
+	mu.Unlock()
+
+	hash := sha1.New()
+	hash.Write([]byte(data))
+	hashBytes := hash.Sum(nil)
+	return binary.BigEndian.Uint64(hashBytes)
+}
+
+// pcgSource is a 128-bit PCG generator with the DXSM output function.
+type pcgSource struct {
+	hi, lo uint64
+}
+
+// newPCGSource seeds a PCG generator with the SHA256 of the seed.
+func newPCGSource(seed string) *pcgSource {
+	sum := sha256.Sum256([]byte(seed))
+	return &pcgSource{
+		hi: binary.BigEndian.Uint64(sum[0:8]),
+		lo: binary.BigEndian.Uint64(sum[8:16]),
+	}
+}
+
+// Uint64 advances the 128-bit LCG state and returns its DXSM permutation.
+func (p *pcgSource) Uint64() uint64 {
+	const (
+		mulHi = 2549297995355413924
+		mulLo = 4865540595714422341
+		incHi = 6364136223846793005
+		incLo = 1442695040888963407
+	)
+
+	// state = state * mul + inc
+	hi, lo := bits.Mul64(p.lo, mulLo)
+	hi += p.hi*mulLo + p.lo*mulHi
+	lo, c := bits.Add64(lo, incLo, 0)
+	hi, _ = bits.Add64(hi, incHi, c)
+	p.lo = lo
+	p.hi = hi
+
+	// DXSM "double xorshift multiply"
+	const cheapMul = 0xda942042e4dd58b5
+	hi ^= hi >> 32
+	hi *= cheapMul
+	hi ^= hi >> 48
+	hi *= (lo | 1)
+	return hi
+}
+
+// keccakSource mirrors uint256(keccak256(abi.encodePacked(seed, tokenId, counter)))
+// as computed by a contract, keeping the 64 most significant bits.
+type keccakSource struct {
+	seed    string
+	tokenID uint64
+	counter uint64
+}
+
+// Uint64 returns the top 8 bytes of keccak256(seed, tokenID, counter) and
+// increments the counter. The token ID and the counter are packed as uint256.
+func (k *keccakSource) Uint64() uint64 {
+	var word [32]byte
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(k.seed))
+	binary.BigEndian.PutUint64(word[24:], k.tokenID)
+	hash.Write(word[:])
+	binary.BigEndian.PutUint64(word[24:], k.counter)
+	hash.Write(word[:])
+	k.counter++
+
+	return binary.BigEndian.Uint64(hash.Sum(nil))
+}
@@ -0,0 +1,158 @@
+package utils
+
+import "math/big"
+
+// Trace records the decisions a Randomizer makes, so a selection can be run
+// again with some of its decisions revised while the others are replayed.
+// Runs must be deterministic: given the same decisions, a run meets the same
+// choice points in the same order.
+type Trace struct {
+	decisions []*decision
+	replay    int    // Index of the decision redrawn by the next run; the ones before it are replayed.
+	pos       int    // Index of the next decision of the current run.
+	label     string // Label given to the next decisions.
+}
+
+// decision is a choice point: the weights of its options, the option taken
+// and the options ruled out by backtracking.
+type decision struct {
+	label   string
+	weights []*big.Rat
+	choice  int
+	banned  []bool
+}
+
+// open reports whether an option that was not ruled out can still be taken.
+func (d *decision) open() bool {
+	for i, w := range d.weights {
+		if !d.banned[i] && w.Sign() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// StartTrace makes the randomizer record its decisions in a new trace.
+func (r *Randomizer) StartTrace() *Trace {
+	r.trace = &Trace{}
+	return r.trace
+}
+
+// StopTrace stops recording decisions.
+func (r *Randomizer) StopTrace() {
+	r.trace = nil
+}
+
+// Label names the decisions that follow, such as the slot being picked, so
+// backtracking can target them. Does nothing when no trace is recorded.
+func (r *Randomizer) Label(label string) {
+	if r.trace != nil {
+		r.trace.label = label
+	}
+}
+
+// Rewind starts a new run of the selection.
+func (t *Trace) Rewind() {
+	t.pos = 0
+	t.label = ""
+}
+
+// Backtrack rules out the choice of the latest decision and revises it on the
+// next run, going back to earlier decisions while a decision has no option
+// left. Returns false when no decision can be revised.
+func (t *Trace) Backtrack() bool {
+	return t.backtrack(func(string) bool { return true })
+}
+
+// BacktrackTo is like Backtrack, but only revises the decisions with one of
+// the given labels. The later decisions are dropped and drawn again.
+func (t *Trace) BacktrackTo(labels ...string) bool {
+	return t.backtrack(func(label string) bool {
+		for _, l := range labels {
+			if l == label {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// backtrack revises the latest decision accepted by match that has an option left.
+func (t *Trace) backtrack(match func(label string) bool) bool {
+	for i := len(t.decisions) - 1; i >= 0; i-- {
+		d := t.decisions[i]
+		if !match(d.label) {
+			continue
+		}
+
+		// A legacy draw can pick nothing, leaving no option to rule out.
+		if d.choice >= 0 {
+			d.banned[d.choice] = true
+		}
+		if d.open() {
+			t.decisions = t.decisions[:i+1]
+			t.replay = i
+			return true
+		}
+	}
+	return false
+}
+
+// decide takes a decision between options of the given weights. Without a
+// trace, or for a new decision, draw takes it from the random stream as
+// usual. A replayed decision takes its recorded option without drawing, and
+// a revised one is drawn again among the options not ruled out, so the
+// sampling stays weighted.
+func (r *Randomizer) decide(weights []*big.Rat, draw func() int) int {
+	t := r.trace
+	if t == nil || !hasPositive(weights) {
+		return draw()
+	}
+
+	defer func() { t.pos++ }()
+
+	if t.pos < len(t.decisions) {
+		d := t.decisions[t.pos]
+		if len(d.weights) != len(weights) {
+			panic("selection is not deterministic: the trace cannot be replayed")
+		}
+
+		if t.pos < t.replay {
+			return d.choice
+		}
+
+		masked := make([]*big.Rat, len(weights))
+		for i, w := range weights {
+			masked[i] = w
+			if d.banned[i] {
+				masked[i] = new(big.Rat)
+			}
+		}
+
+		d.choice = r.pick(masked)
+		if d.choice < 0 {
+			panic("should not happen")
+		}
+		return d.choice
+	}
+
+	d := &decision{
+		label:   t.label,
+		weights: weights,
+		choice:  draw(),
+		banned:  make([]bool, len(weights)),
+	}
+	t.decisions = append(t.decisions, d)
+
+	return d.choice
+}
+
+// hasPositive reports whether any weight is positive.
+func hasPositive(weights []*big.Rat) bool {
+	for _, w := range weights {
+		if w.Sign() > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -1,19 +1,16 @@
 package utils
 
 import (
-	"crypto/sha1"
 	"encoding/binary"
+	"fmt"
 	"generator/models"
+	"math/big"
 	"strconv"
 	"sync"
 	"time"
 )
 
-const (
-	timeFormat          = "2006-01-02 15:04:05"
-	one_hundred_percent = 100000
-	retryCount          = 10
-)
+const timeFormat = "2006-01-02 15:04:05"
 
 func MustParseTime(timeString string) time.Time {
 	t, err := time.Parse(timeFormat, timeString)
@@ -24,10 +21,10 @@ func MustParseTime(timeString string) time.Time {
 }
 
 type Randomizer struct {
-	Seed     string
-	withTime bool
-	mu       sync.Mutex
-	Counter  int
+	Seed   string
+	Kind   SourceKind
+	source Source
+	trace  *Trace // Decisions being recorded, if any.
 }
 
 var (
@@ -47,9 +44,21 @@ func IsRandomizerDone() bool {
 	return done
 }
 
+// NewRandomizer creates a randomizer drawing from the sha1(seed + counter) stream.
 func NewRandomizer(seed string) *Randomizer {
+	return NewRandomizerWith(SourceSHA1, seed, 0)
+}
+
+// NewRandomizerWith creates a randomizer drawing from a source of the given kind.
+// The token ID is only used by the sources that mix it into their stream.
+func NewRandomizerWith(kind SourceKind, seed string, tokenID int) *Randomizer {
+	if kind == "" {
+		kind = SourceSHA1
+	}
 	return &Randomizer{
-		Seed: seed,
+		Seed:   seed,
+		Kind:   kind,
+		source: NewSource(kind, seed, tokenID),
 	}
 }
 
@@ -57,108 +66,252 @@ func (r *Randomizer) RandomNumberBy(max int) int {
 	return r.RandomNumber(max - 1)
 }
 
+// RandomNumber returns a uniformly distributed number in [0, max]. The
+// legacy source takes the plain modulo, as the original sampler did.
 func (r *Randomizer) RandomNumber(max int) int {
-	mu.Lock()
-	var data = r.Seed + strconv.Itoa(r.Counter)
-	r.Counter++
-	mu.Unlock()
-
-	hash := sha1.New()
-	hash.Write([]byte(data))
-	hashBytes := hash.Sum(nil)
-	randomNumber := binary.BigEndian.Uint64(hashBytes)
-	return int(randomNumber % uint64(max+1))
+	if r.legacy() {
+		return r.legacyNumber(max)
+	}
+	return int(r.Uint64n(uint64(max) + 1))
 }
 
-func (r *Randomizer) RandomCategory() models.Category {
-	var percentages []int
-
-	var lastPercentage int
-
-	list := models.CategoryList()
-
-	for _, v := range list {
-		percent := v.ToPercentage()
+// Uint64 returns the next 64 bits of the random stream.
+func (r *Randomizer) Uint64() uint64 {
+	return r.source.Uint64()
+}
 
-		percentNew := lastPercentage + percent
+// Uint64n returns a uniformly distributed number in [0, n). It rejects the
+// lowest 2^64 mod n values of the stream, which would otherwise make the
+// small results of the modulo slightly more likely.
+func (r *Randomizer) Uint64n(n uint64) uint64 {
+	if n == 0 {
+		panic("Uint64n: n must be positive")
+	}
 
-		percentages = append(percentages, percentNew)
+	threshold := -n % n // 2^64 mod n
+	for {
+		if v := r.Uint64(); v >= threshold {
+			return v % n
+		}
+	}
+}
 
-		lastPercentage = percentNew
+// BigN returns a uniformly distributed number in [0, n), for bounds that may not fit in 64 bits.
+// Candidates with as many bits as n are drawn from the stream until one is below n.
+func (r *Randomizer) BigN(n *big.Int) *big.Int {
+	if n.Sign() <= 0 {
+		panic("BigN: n must be positive")
+	}
+	if n.IsUint64() {
+		return new(big.Int).SetUint64(r.Uint64n(n.Uint64()))
 	}
 
-	randomNumber := r.RandomNumber(100)
+	bits := n.BitLen()
+	words := (bits + 63) / 64
+	buf := make([]byte, words*8)
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bits)), big.NewInt(1))
 
-	for i, v := range percentages {
-		if randomNumber <= int(v) {
-			return list[i]
+	for {
+		for i := 0; i < words; i++ {
+			binary.BigEndian.PutUint64(buf[i*8:], r.Uint64())
+		}
+		v := new(big.Int).SetBytes(buf)
+		v.And(v, mask)
+		if v.Cmp(n) < 0 {
+			return v
 		}
 	}
+}
 
-	panic("should not happen")
+// Chance reports true with the given probability, in percent.
+// The probability is an exact rational, so no precision is lost.
+func (r *Randomizer) Chance(percentage *big.Rat) bool {
+	return r.chanceWith(percentage, func() bool { return r.chance(percentage) })
 }
 
-func (r *Randomizer) RandomGender() models.Gender {
-	randomNumber := r.RandomNumber(100)
+// chanceWith takes the decision of Chance, drawing it with draw.
+func (r *Randomizer) chanceWith(percentage *big.Rat, draw func() bool) bool {
+	p := new(big.Rat).Quo(percentage, big.NewRat(100, 1))
+	weights := []*big.Rat{p, new(big.Rat).Sub(big.NewRat(1, 1), p)}
 
-	if randomNumber < models.GenderMale.ToPercentage() {
-		return models.GenderMale
+	return r.decide(weights, func() int {
+		if draw() {
+			return 0
+		}
+		return 1
+	}) == 0
+}
+
+// chance draws the outcome of Chance from the random stream.
+func (r *Randomizer) chance(percentage *big.Rat) bool {
+	if percentage.Sign() <= 0 {
+		return false
+	}
+
+	p := new(big.Rat).Quo(percentage, big.NewRat(100, 1))
+	if p.Cmp(big.NewRat(1, 1)) >= 0 {
+		return true
 	}
 
-	return models.GenderFemale
+	return r.BigN(p.Denom()).Cmp(p.Num()) < 0
 }
 
-func (r *Randomizer) HasHair(percentage int) bool {
-	randomNumber := r.RandomNumber(100)
-	return randomNumber < percentage
+// Pick returns the index of an entry picked according to the given weights,
+// or -1 if no weight is positive. The weights are brought to a common
+// denominator and an integer is drawn uniformly below their sum, so every
+// entry is picked with exactly its share of the total.
+func (r *Randomizer) Pick(weights []*big.Rat) int {
+	return r.decide(weights, func() int { return r.pick(weights) })
 }
 
-func (r *Randomizer) Random(data []*models.Common, na *models.Common) *models.Common {
-	var percentages []float64
+// pick draws the outcome of Pick from the random stream.
+func (r *Randomizer) pick(weights []*big.Rat) int {
+	denominator := big.NewInt(1)
+	for _, w := range weights {
+		if w.Sign() > 0 {
+			denominator = lcm(denominator, w.Denom())
+		}
+	}
+
+	scaled := make([]*big.Int, len(weights))
+	total := new(big.Int)
+	for i, w := range weights {
+		scaled[i] = new(big.Int)
+		if w.Sign() > 0 {
+			scaled[i].Mul(w.Num(), new(big.Int).Quo(denominator, w.Denom()))
+		}
+		total.Add(total, scaled[i])
+	}
 
-	var lastPercentage float64
+	if total.Sign() == 0 {
+		return -1
+	}
 
-	if na != nil && na.Distribution.GetPercentage() != 0 {
-		randomNumber := r.RandomNumber(100000)
+	randomNumber := r.BigN(total)
 
-		if randomNumber < int(na.Distribution.GetPercentage()*1000) {
-			return nil
+	cumulative := new(big.Int)
+	for i, v := range scaled {
+		cumulative.Add(cumulative, v)
+		if randomNumber.Cmp(cumulative) < 0 {
+			return i
 		}
 	}
 
-	distributions := NormalizeDistribution(data)
+	panic("should not happen")
+}
+
+// RandomCategory picks a category according to the given weights, in percent.
+func (r *Randomizer) RandomCategory(weights map[models.Category]float64) models.Category {
+	list := models.CategoryList()
+
+	rats := make([]*big.Rat, 0, len(list))
+	for _, v := range list {
+		rats = append(rats, decimal(weights[v]))
+	}
+
+	i := r.decide(rats, func() int {
+		if r.legacy() {
+			return r.legacyCategory(rats)
+		}
+		return r.pick(rats)
+	})
+	if i >= 0 {
+		return list[i]
+	}
 
-	for i := range data {
-		// TODO this can fail
-		percent := distributions[i]
+	panic("should not happen")
+}
 
-		percentNew := lastPercentage + percent*1000
+// RandomGender picks a gender according to the given weights, in percent.
+func (r *Randomizer) RandomGender(weights map[models.Gender]float64) models.Gender {
+	list := []models.Gender{models.GenderMale, models.GenderFemale}
 
-		percentages = append(percentages, percentNew)
+	rats := make([]*big.Rat, 0, len(list))
+	for _, v := range list {
+		rats = append(rats, decimal(weights[v]))
+	}
 
-		lastPercentage = percentNew
+	i := r.decide(rats, func() int {
+		if r.legacy() {
+			return r.legacyGender(rats)
+		}
+		return r.pick(rats)
+	})
+	if i >= 0 {
+		return list[i]
 	}
 
-	percentages = append(percentages, 100*1000)
+	panic("should not happen")
+}
 
-	for i := 0; i < retryCount; i++ {
-		randomNumber := r.RandomNumber(100 * 1000)
-		length := len(data)
+// HasHair reports whether a token has hair, given its chance in percent.
+func (r *Randomizer) HasHair(percentage float64) bool {
+	if r.legacy() {
+		return r.chanceWith(decimal(percentage), func() bool { return r.legacyChance(percentage, 100) })
+	}
+	return r.Chance(decimal(percentage))
+}
 
-		for i, v := range percentages {
-			if randomNumber <= int(v) && i < length {
-				return data[i]
-			}
+// decimal returns the exact rational of the shortest decimal text of a
+// configured weight, so 0.1 is 1/10 rather than its binary approximation.
+func decimal(weight float64) *big.Rat {
+	result, ok := new(big.Rat).SetString(strconv.FormatFloat(weight, 'f', -1, 64))
+	if !ok {
+		panic(fmt.Errorf("invalid weight: %v", weight))
+	}
+	return result
+}
+
+// Random picks an item according to the Distribution of every item. When na
+// is given, its Distribution is the chance of picking nothing at all.
+// Returns nil if nothing is picked or no item has a positive distribution.
+//
+// The legacy source draws as the original sampler did: the chance of nothing
+// and the items are drawn in [0, 100000] against float percentages.
+func (r *Randomizer) Random(data []*models.Common, na *models.Common) *models.Common {
+	if r.legacy() {
+		if na != nil && na.Distribution.GetPercentage() != 0 && r.chanceWith(na.Distribution.GetRat(), func() bool {
+			return r.legacyChance(na.Distribution.GetPercentage(), 100*1000)
+		}) {
+			return nil
 		}
+	} else if na != nil && r.Chance(na.Distribution.GetRat()) {
+		return nil
+	}
+
+	weights := make([]*big.Rat, 0, len(data))
+	for _, value := range data {
+		weights = append(weights, value.Distribution.GetRat())
+	}
 
-		if done {
-			break
+	i := r.decide(weights, func() int {
+		if r.legacy() {
+			return r.legacyRandom(data)
 		}
+		return r.pick(weights)
+	})
+	if i >= 0 {
+		return data[i]
 	}
 
 	return nil
 }
 
+// Permutation returns a uniformly random permutation of [0, n), built with a Fisher–Yates shuffle.
+func (r *Randomizer) Permutation(n int) []int {
+	result := make([]int, n)
+	for i := range result {
+		result[i] = i
+	}
+
+	for i := n - 1; i > 0; i-- {
+		j := int(r.Uint64n(uint64(i) + 1))
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return result
+}
+
 func NormalizeDistribution(data []*models.Common) []float64 {
 	sum := 0.0
 
@@ -176,3 +329,9 @@ func NormalizeDistribution(data []*models.Common) []float64 {
 
 	return percentages
 }
+
+// lcm returns the least common multiple of two positive integers.
+func lcm(a, b *big.Int) *big.Int {
+	gcd := new(big.Int).GCD(nil, nil, a, b)
+	return new(big.Int).Mul(a, new(big.Int).Quo(b, gcd))
+}
@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"generator/models"
+	"math"
+	"math/big"
+	"strconv"
+	"testing"
+)
+
+// samples is the number of draws of every statistical test. The seed is
+// fixed, so the tests are deterministic; the bound of checkFrequency keeps
+// them meaningful for any seed.
+const samples = 200000
+
+// newTestRandomizer returns a randomizer of a fixed seed.
+func newTestRandomizer() *Randomizer {
+	return NewRandomizerWith(SourcePCG, "statistical-tests", 0)
+}
+
+// checkFrequency fails when a count is more than five standard deviations
+// away from the count expected with probability p over n draws.
+func checkFrequency(t *testing.T, name string, count, n int, p *big.Rat) {
+	t.Helper()
+
+	expected, _ := p.Float64()
+	mean := float64(n) * expected
+	deviation := math.Sqrt(float64(n) * expected * (1 - expected))
+
+	if math.Abs(float64(count)-mean) > 5*deviation {
+		t.Errorf("%s: drawn %d times out of %d, expected %.1f ± %.1f", name, count, n, mean, 5*deviation)
+	}
+}
+
+func TestUint64n(t *testing.T) {
+	r := newTestRandomizer()
+
+	counts := make([]int, 7)
+	for i := 0; i < samples; i++ {
+		counts[r.Uint64n(7)]++
+	}
+	for v, count := range counts {
+		checkFrequency(t, fmt.Sprintf("Uint64n(7) = %d", v), count, samples, big.NewRat(1, 7))
+	}
+}
+
+func TestUint64nWithoutModuloBias(t *testing.T) {
+	r := newTestRandomizer()
+
+	// With n = 3·2^62, a plain modulo would land in the first third half of the time.
+	n := uint64(3) << 62
+	counts := make([]int, 3)
+	for i := 0; i < samples; i++ {
+		counts[r.Uint64n(n)>>62]++
+	}
+	for third, count := range counts {
+		checkFrequency(t, fmt.Sprintf("Uint64n(3·2^62) third %d", third), count, samples, big.NewRat(1, 3))
+	}
+}
+
+func TestPick(t *testing.T) {
+	r := newTestRandomizer()
+
+	weights := []*big.Rat{big.NewRat(1, 3), big.NewRat(1, 6), new(big.Rat), big.NewRat(49, 100), big.NewRat(1, 100)}
+	total := new(big.Rat)
+	for _, w := range weights {
+		total.Add(total, w)
+	}
+
+	counts := make([]int, len(weights))
+	for i := 0; i < samples; i++ {
+		counts[r.Pick(weights)]++
+	}
+
+	if counts[2] != 0 {
+		t.Errorf("an entry of weight 0 was picked %d times", counts[2])
+	}
+	for i, w := range weights {
+		checkFrequency(t, "Pick "+w.RatString(), counts[i], samples, new(big.Rat).Quo(w, total))
+	}
+}
+
+func TestPickWithoutWeight(t *testing.T) {
+	r := newTestRandomizer()
+
+	if i := r.Pick([]*big.Rat{new(big.Rat), new(big.Rat)}); i != -1 {
+		t.Errorf("picked %d among weights of 0, expected -1", i)
+	}
+}
+
+func TestChance(t *testing.T) {
+	r := newTestRandomizer()
+
+	for _, percentage := range []*big.Rat{big.NewRat(25, 2), big.NewRat(82, 1), big.NewRat(1, 3)} {
+		count := 0
+		for i := 0; i < samples; i++ {
+			if r.Chance(percentage) {
+				count++
+			}
+		}
+		checkFrequency(t, "Chance "+percentage.RatString()+"%", count, samples, new(big.Rat).Quo(percentage, big.NewRat(100, 1)))
+	}
+}
+
+func TestChanceBounds(t *testing.T) {
+	r := newTestRandomizer()
+
+	for i := 0; i < 1000; i++ {
+		if r.Chance(new(big.Rat)) {
+			t.Fatal("Chance(0%) returned true")
+		}
+		if !r.Chance(big.NewRat(100, 1)) {
+			t.Fatal("Chance(100%) returned false")
+		}
+	}
+}
+
+func TestRandomFollowsDistribution(t *testing.T) {
+	r := newTestRandomizer()
+
+	data := []*models.Common{
+		{OpenSeaTraitValue: "A", Distribution: "62.5%"},
+		{OpenSeaTraitValue: "B", Distribution: "37.4%"},
+		{OpenSeaTraitValue: "C", Distribution: "0.1%"},
+	}
+	na := &models.Common{Distribution: "20%"}
+
+	counts := make(map[string]int)
+	for i := 0; i < samples; i++ {
+		if picked := r.Random(data, na); picked != nil {
+			counts[picked.OpenSeaTraitValue]++
+		} else {
+			counts["NA"]++
+		}
+	}
+
+	checkFrequency(t, "NA", counts["NA"], samples, big.NewRat(1, 5))
+	for _, common := range data {
+		// Picked among the items with the chance left by NA.
+		p := new(big.Rat).Mul(common.Distribution.GetRat(), big.NewRat(80, 100*100))
+		checkFrequency(t, common.OpenSeaTraitValue, counts[common.OpenSeaTraitValue], samples, p)
+	}
+}
+
+func TestDecimalWeights(t *testing.T) {
+	if got := decimal(0.1); got.Cmp(big.NewRat(1, 10)) != 0 {
+		t.Errorf("decimal(0.1) = %s, expected 1/10", got.RatString())
+	}
+	if got := decimal(82); got.Cmp(big.NewRat(82, 1)) != 0 {
+		t.Errorf("decimal(82) = %s, expected 82", got.RatString())
+	}
+}
+
+// originalSampler is the sampler of the runs that recorded no source, kept
+// to check that SourceSHA1Legacy draws the same way.
+type originalSampler struct {
+	seed    string
+	counter int
+}
+
+func (o *originalSampler) number(max int) int {
+	sum := sha1.Sum([]byte(o.seed + strconv.Itoa(o.counter)))
+	o.counter++
+	return int(binary.BigEndian.Uint64(sum[:]) % uint64(max+1))
+}
+
+func (o *originalSampler) category() models.Category {
+	randomNumber := o.number(100)
+	cumulative := 0
+	for _, category := range models.CategoryList() {
+		cumulative += category.ToPercentage()
+		if randomNumber <= cumulative {
+			return category
+		}
+	}
+	panic("should not happen")
+}
+
+func (o *originalSampler) random(data []*models.Common, na *models.Common) *models.Common {
+	if na != nil && na.Distribution.GetPercentage() != 0 {
+		if o.number(100000) < int(na.Distribution.GetPercentage()*1000) {
+			return nil
+		}
+	}
+
+	var percentages []float64
+	var lastPercentage float64
+	for _, percent := range NormalizeDistribution(data) {
+		lastPercentage += percent * 1000
+		percentages = append(percentages, lastPercentage)
+	}
+
+	for i := 0; i < 10; i++ {
+		randomNumber := o.number(100 * 1000)
+		for j, v := range percentages {
+			if randomNumber <= int(v) {
+				return data[j]
+			}
+		}
+	}
+	return nil
+}
+
+func TestLegacyMatchesOriginalSampler(t *testing.T) {
+	const seed = "legacy-replay"
+	r := NewRandomizerWith(SourceSHA1Legacy, seed, 0)
+	o := &originalSampler{seed: seed}
+
+	categories := map[models.Category]float64{
+		models.CategoryCool: 50, models.CategorySpooky: 20, models.CategoryGoofy: 10, models.CategoryCute: 20,
+	}
+	genders := map[models.Gender]float64{models.GenderMale: 82, models.GenderFemale: 18}
+	data := []*models.Common{
+		{OpenSeaTraitValue: "A", Distribution: "62.5%"},
+		{OpenSeaTraitValue: "B", Distribution: "37.4%"},
+		{OpenSeaTraitValue: "C", Distribution: "0.1%"},
+	}
+	na := &models.Common{Distribution: "29%"}
+
+	for i := 0; i < 2000; i++ {
+		if got, expected := r.RandomCategory(categories), o.category(); got != expected {
+			t.Fatalf("draw %d: category %s, expected %s", i, got, expected)
+		}
+
+		expectedGender := models.GenderFemale
+		if o.number(100) < 82 {
+			expectedGender = models.GenderMale
+		}
+		if got := r.RandomGender(genders); got != expectedGender {
+			t.Fatalf("draw %d: gender %s, expected %s", i, got, expectedGender)
+		}
+
+		if got, expected := r.HasHair(50), o.number(100) < 50; got != expected {
+			t.Fatalf("draw %d: hair %v, expected %v", i, got, expected)
+		}
+
+		if got, expected := r.Random(data, na), o.random(data, na); got != expected {
+			t.Fatalf("draw %d: picked %v, expected %v", i, got, expected)
+		}
+	}
+}